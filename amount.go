@@ -0,0 +1,210 @@
+package sbanken
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact amount of money, stored as whole øre to avoid
+// the rounding errors that come with float64. It is read from and
+// written to JSON as a decimal number, just like the float64 fields
+// it replaces. Existing code using float64 can convert with
+// AmountFromFloat and Amount.Float64.
+//
+//	total := sbanken.Amount(0)
+//	for _, tx := range transactions {
+//		total = total.Add(tx.Amount)
+//	}
+//	fmt.Println(total) // -1 234,56 kr
+type Amount int64
+
+// Ore and Krone are the units of an Amount, so that 12 kr 50 øre
+// can be written as 12*Krone + 50*Ore
+const (
+	Ore   Amount = 1
+	Krone Amount = 100
+)
+
+// AmountFromFloat converts f, given in kroner, to the nearest Amount
+func AmountFromFloat(f float64) Amount {
+	return Amount(math.Round(f * 100))
+}
+
+// ParseAmount parses a decimal amount in kroner, like "-58.000" as
+// returned from Sbanken, or "1 234,56" as written in Norway. Amounts
+// with more than two decimals are rounded to the nearest øre
+func ParseAmount(s string) (Amount, error) {
+	orig := s
+	s = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "kr")
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid amount %q: %w", orig, err)
+		}
+		if math.Abs(f) >= math.MaxInt64/100 {
+			return 0, fmt.Errorf("Amount %q is out of range", orig)
+		}
+		return AmountFromFloat(f), nil
+	}
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimLeft(s, "+-")
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("Invalid amount %q", orig)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if !isDigits(whole) || (frac != "" && !isDigits(frac)) {
+		return 0, fmt.Errorf("Invalid amount %q", orig)
+	}
+	round := len(frac) > 2 && frac[2] >= '5'
+	frac = (frac + "00")[:2]
+	kroner, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid amount %q: %w", orig, err)
+	}
+	if kroner > (math.MaxInt64-99)/100 {
+		return 0, fmt.Errorf("Amount %q is out of range", orig)
+	}
+	ore, _ := strconv.ParseInt(frac, 10, 64)
+	a := Amount(kroner*100 + ore)
+	if round {
+		a++
+	}
+	if negative {
+		a = -a
+	}
+	return a, nil
+}
+
+// Float64 returns the amount in kroner as a float64
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+// Add returns a + b
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+// Sub returns a - b
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Abs returns the absolute value of a
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Mul returns a multiplied by n
+func (a Amount) Mul(n int64) Amount {
+	return a * Amount(n)
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than,
+// equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of a
+func (a Amount) Sign() int {
+	return a.Cmp(0)
+}
+
+// IsZero reports whether a is zero
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// Kroner returns the whole kroner of a, truncated towards zero
+func (a Amount) Kroner() int64 {
+	return int64(a / Krone)
+}
+
+// Decimal returns the amount as a plain decimal number in kroner,
+// like -1234.56
+func (a Amount) Decimal() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	abs := a.Abs()
+	return fmt.Sprintf("%s%d.%02d", sign, abs/Krone, abs%Krone)
+}
+
+// String formats the amount the Norwegian way, like -1 234,56 kr
+func (a Amount) String() string {
+	sign := ""
+	if a < 0 {
+		sign = "-"
+	}
+	abs := a.Abs()
+	whole := strconv.FormatInt(int64(abs/Krone), 10)
+	var grouped strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(' ')
+		}
+		grouped.WriteRune(c)
+	}
+	return fmt.Sprintf("%s%s,%02d kr", sign, grouped.String(), abs%Krone)
+}
+
+// MarshalJSON writes the amount as a decimal number
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.Decimal()), nil
+}
+
+// UnmarshalJSON reads a decimal number, or a string holding one,
+// without going through float64
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" || s == "" {
+		*a = 0
+		return nil
+	}
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalText writes the amount as a decimal number
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.Decimal()), nil
+}
+
+// UnmarshalText reads an amount in any format accepted by ParseAmount
+func (a *Amount) UnmarshalText(b []byte) error {
+	parsed, err := ParseAmount(string(b))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
@@ -1,10 +1,11 @@
 package sbanken
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 )
 
-const cards = "https://publicapi.sbanken.no/apibeta/api/v1/Cards"
+const cards = "/Cards"
 
 // Card ...
 type Card struct {
@@ -13,7 +14,7 @@ type Card struct {
 	CardVersionNumber string `json:"cardVersionNumber"`
 	AccountNumber     string `json:"accountNumber"`
 	CustomerID        string `json:"customerId"`
-	ExpiryDate        string `json:"expiryDate"`
+	ExpiryDate        Date   `json:"expiryDate"`
 	AccountOwner      string `json:"accountOwner"`
 	Status            string `json:"status"`
 	CardType          string `json:"cardType"`
@@ -33,13 +34,36 @@ type cardItemResponse struct {
 
 // GetCards ...
 func (conn *APIConnection) GetCards() ([]Card, error) {
-	r := newAPIRequest()
-	r.target = cards
+	return conn.GetCardsContext(context.Background())
+}
+
+// GetCardsContext is like GetCards, but the request is bound to ctx
+func (conn *APIConnection) GetCardsContext(ctx context.Context) ([]Card, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(cards)
 	var a cardListResponse
-	resp, err := conn.makeAPIRequest(r)
-	if err != nil {
+	if err := conn.requestJSON(r, &a); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &a)
 	return a.Items, nil
 }
+
+// GetCard returns a single card. The returned error matches
+// ErrNotFound if there is no such card
+func (conn *APIConnection) GetCard(cardID string) (Card, error) {
+	return conn.GetCardContext(context.Background(), cardID)
+}
+
+// GetCardContext is like GetCard, but the request is bound to ctx
+func (conn *APIConnection) GetCardContext(ctx context.Context, cardID string) (Card, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(cards + "/" + cardID)
+	var a cardItemResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return Card{}, err
+	}
+	if a.Item.CardID == "" {
+		return Card{}, fmt.Errorf("Card %s was not found: %w", cardID, ErrNotFound)
+	}
+	return a.Item, nil
+}
@@ -0,0 +1,328 @@
+// Package categorize assigns spending categories to transactions using
+// a list of rules, like
+//
+//	rules:
+//	  - name: rema
+//	    category: Groceries
+//	    priority: 10
+//	    contains: [REMA 1000]
+//	  - name: transport-mcc
+//	    category: Transport
+//	    mcc: ["4111", "4131"]
+//
+// A rule matches when all the conditions it has are met. Within a
+// condition, like contains or mcc, it is enough that one value matches.
+// The rule with the highest priority wins, and rules with the same
+// priority are tried in order.
+package categorize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	sbanken "github.com/elzapp/go-sbanken"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule assigns Category to the transactions it matches
+type Rule struct {
+	Name     string `json:"name" yaml:"name"`
+	Category string `json:"category" yaml:"category"`
+	Priority int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+	// Contains matches GetText, ignoring case
+	Contains []string `json:"contains,omitempty" yaml:"contains,omitempty"`
+	// Regex matches GetText. Add (?i) to ignore case
+	Regex string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	// MCC matches the merchant category code of card transactions.
+	// Ranges like 3000-3299 are allowed, and codes are compared as
+	// parsed by sbanken.ParseMCC
+	MCC []string `json:"mcc,omitempty" yaml:"mcc,omitempty"`
+	// Merchant matches the merchant name of card transactions,
+	// ignoring case
+	Merchant []string `json:"merchant,omitempty" yaml:"merchant,omitempty"`
+	// MinAmount and MaxAmount limit the amount, including the limits.
+	// Spending is negative
+	MinAmount *sbanken.Amount `json:"minAmount,omitempty" yaml:"minAmount,omitempty"`
+	MaxAmount *sbanken.Amount `json:"maxAmount,omitempty" yaml:"maxAmount,omitempty"`
+	// TransactionTypeCode matches the transaction type, like 710 for
+	// card purchases
+	TransactionTypeCode []int64 `json:"transactionTypeCode,omitempty" yaml:"transactionTypeCode,omitempty"`
+	// OtherAccountNumber matches the counterparty account
+	OtherAccountNumber []string `json:"otherAccountNumber,omitempty" yaml:"otherAccountNumber,omitempty"`
+}
+
+// RuleSet is the format of rule files
+type RuleSet struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Engine categorizes transactions. It is safe for concurrent use
+type Engine struct {
+	rules []compiledRule
+}
+
+// Explanation tells why a transaction got its category
+type Explanation struct {
+	Category string
+	// Rule is the rule that fired, or nil if no rule matched
+	Rule *Rule
+	// Reasons are the conditions of Rule that matched
+	Reasons []string
+}
+
+// String returns a line like
+// Groceries (rule "rema", priority 10: text contains "REMA 1000")
+func (e Explanation) String() string {
+	if e.Rule == nil {
+		return "no rule matched"
+	}
+	return fmt.Sprintf("%s (rule %q, priority %d: %s)", e.Category, e.Rule.Name, e.Rule.Priority, strings.Join(e.Reasons, ", "))
+}
+
+type compiledRule struct {
+	Rule
+	conditions []condition
+}
+
+// condition checks a single condition of a rule, returning a reason if
+// it matches
+type condition func(tx *sbanken.Transaction) (string, bool)
+
+// New returns an Engine using rules. An error is returned if a rule has
+// no category, no conditions or an invalid regex
+func New(rules []Rule) (*Engine, error) {
+	e := &Engine{}
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if r.Category == "" {
+			return nil, fmt.Errorf("Rule %s has no category", name)
+		}
+		c, err := compile(r)
+		if err != nil {
+			return nil, fmt.Errorf("Rule %s %w", name, err)
+		}
+		e.rules = append(e.rules, c)
+	}
+	sort.SliceStable(e.rules, func(i, j int) bool {
+		return e.rules[i].Priority > e.rules[j].Priority
+	})
+	return e, nil
+}
+
+// Parse reads rules in YAML or JSON, in the format of RuleSet
+func Parse(data []byte) ([]Rule, error) {
+	// YAML is a superset of JSON, so this reads both
+	var set RuleSet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Failed to read rules: %w", err)
+	}
+	return set.Rules, nil
+}
+
+// Load returns an Engine with the rules in the YAML or JSON file at path
+func Load(path string) (*Engine, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return New(rules)
+}
+
+// Rules returns the rules of the engine, ordered by priority
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, len(e.rules))
+	for i, r := range e.rules {
+		rules[i] = r.Rule
+	}
+	return rules
+}
+
+// Categorize returns the category of tx, or an empty string if no rule
+// matches
+func (e *Engine) Categorize(tx *sbanken.Transaction) string {
+	for i := range e.rules {
+		if _, ok := e.rules[i].match(tx); ok {
+			return e.rules[i].Category
+		}
+	}
+	return ""
+}
+
+// Explain is like Categorize, but tells which rule fired and why
+func (e *Engine) Explain(tx *sbanken.Transaction) Explanation {
+	for i := range e.rules {
+		if reasons, ok := e.rules[i].match(tx); ok {
+			rule := e.rules[i].Rule
+			return Explanation{Category: rule.Category, Rule: &rule, Reasons: reasons}
+		}
+	}
+	return Explanation{}
+}
+
+// compile turns the conditions set on r into condition functions
+func compile(r Rule) (compiledRule, error) {
+	c := compiledRule{Rule: r}
+	if len(r.Contains) > 0 {
+		contains := make([]string, len(r.Contains))
+		for i, s := range r.Contains {
+			contains[i] = strings.ToUpper(s)
+		}
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			text := strings.ToUpper(tx.GetText())
+			for i, s := range contains {
+				if strings.Contains(text, s) {
+					return fmt.Sprintf("text contains %q", r.Contains[i]), true
+				}
+			}
+			return "", false
+		})
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return c, fmt.Errorf("has an invalid regex: %w", err)
+		}
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			if re.MatchString(tx.GetText()) {
+				return fmt.Sprintf("text matches %q", r.Regex), true
+			}
+			return "", false
+		})
+	}
+	if len(r.MCC) > 0 {
+		ranges := make([]mccRange, len(r.MCC))
+		for i, s := range r.MCC {
+			mr, err := parseMCCRange(s)
+			if err != nil {
+				return c, err
+			}
+			ranges[i] = mr
+		}
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			mcc := tx.MCC()
+			if mcc == "" {
+				return "", false
+			}
+			n, _ := strconv.Atoi(string(mcc))
+			for i, mr := range ranges {
+				if n >= mr.from && n <= mr.to {
+					if mr.from == mr.to {
+						return fmt.Sprintf("MCC is %s", mcc), true
+					}
+					return fmt.Sprintf("MCC %s is in %s", mcc, r.MCC[i]), true
+				}
+			}
+			return "", false
+		})
+	}
+	if len(r.Merchant) > 0 {
+		merchants := make([]string, len(r.Merchant))
+		for i, s := range r.Merchant {
+			merchants[i] = strings.ToUpper(s)
+		}
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			name := strings.ToUpper(tx.CardDetails.MerchantName)
+			for i, s := range merchants {
+				if name != "" && strings.Contains(name, s) {
+					return fmt.Sprintf("merchant contains %q", r.Merchant[i]), true
+				}
+			}
+			return "", false
+		})
+	}
+	if r.MinAmount != nil || r.MaxAmount != nil {
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			if r.MinAmount != nil && tx.Amount < *r.MinAmount {
+				return "", false
+			}
+			if r.MaxAmount != nil && tx.Amount > *r.MaxAmount {
+				return "", false
+			}
+			return fmt.Sprintf("amount %s is in range", tx.Amount.Decimal()), true
+		})
+	}
+	if len(r.TransactionTypeCode) > 0 {
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			for _, code := range r.TransactionTypeCode {
+				if tx.TransactionTypeCode == code {
+					return fmt.Sprintf("transaction type is %d", code), true
+				}
+			}
+			return "", false
+		})
+	}
+	if len(r.OtherAccountNumber) > 0 {
+		accounts := make([]string, len(r.OtherAccountNumber))
+		for i, s := range r.OtherAccountNumber {
+			accounts[i] = sbanken.NormalizeAccountNumber(s)
+		}
+		c.add(func(tx *sbanken.Transaction) (string, bool) {
+			other := sbanken.NormalizeAccountNumber(tx.OtherAccountNumber)
+			for i, a := range accounts {
+				if other != "" && other == a {
+					return fmt.Sprintf("other account is %s", r.OtherAccountNumber[i]), true
+				}
+			}
+			return "", false
+		})
+	}
+	if len(c.conditions) == 0 {
+		return c, fmt.Errorf("has no conditions")
+	}
+	return c, nil
+}
+
+// mccRange is an inclusive range of merchant category codes
+type mccRange struct {
+	from, to int
+}
+
+// parseMCCRange reads a single code, like 5411, or a range, like
+// 3000-3299
+func parseMCCRange(s string) (mccRange, error) {
+	parts := strings.SplitN(s, "-", 2)
+	var mr mccRange
+	for i, p := range parts {
+		mcc := sbanken.ParseMCC(p)
+		if mcc == "" {
+			return mr, fmt.Errorf("has an invalid MCC %q", s)
+		}
+		n, _ := strconv.Atoi(string(mcc))
+		if i == 0 {
+			mr.from = n
+		}
+		mr.to = n
+	}
+	if mr.to < mr.from {
+		return mr, fmt.Errorf("has an invalid MCC range %q", s)
+	}
+	return mr, nil
+}
+
+func (r *compiledRule) add(cond condition) {
+	r.conditions = append(r.conditions, cond)
+}
+
+// match returns the reasons tx matches the rule, if it does
+func (r *compiledRule) match(tx *sbanken.Transaction) ([]string, bool) {
+	var reasons []string
+	for _, cond := range r.conditions {
+		reason, ok := cond(tx)
+		if !ok {
+			return nil, false
+		}
+		reasons = append(reasons, reason)
+	}
+	return reasons, true
+}
@@ -0,0 +1,163 @@
+package categorize
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+func cardTx(text, mcc, merchant string, amount sbanken.Amount) *sbanken.Transaction {
+	tx := &sbanken.Transaction{Text: text, Amount: amount, TransactionTypeCode: 710, CardDetailsSpecified: true}
+	tx.CardDetails.MerchantCategoryCode = mcc
+	tx.CardDetails.MerchantName = merchant
+	return tx
+}
+
+func TestDefaultRules(t *testing.T) {
+	cases := []struct {
+		tx       *sbanken.Transaction
+		expected string
+	}{
+		{cardTx("23.10 REMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN", "5411", "REMA SPECTRUM", -1641), "Groceries"},
+		{cardTx("*1234 22.10 NOK 89.90 KIWI 505 BERGEN Kurs: 1.0000", "", "", -8990), "Groceries"},
+		{cardTx("VINMONOPOLET OSLO", "5921", "VINMONOPOLET", -29900), "Alcohol"},
+		{cardTx("VY APP", "4112", "", -4400), "Transport"},
+		{cardTx("NSB BILLETT", "", "", -4400), "Transport"},
+		{cardTx("ENVY CLOTHING", "5651", "", -50000), ""},
+		{cardTx("UNKNOWN BAKERY", "5462", "", -5000), ""},
+		{cardTx("PIZZABAKEREN", "5814", "", -19900), "Restaurants"},
+		{cardTx("RESTAURANT", " 05812", "", -19900), "Restaurants"},
+		{cardTx("ICE CREAM BAR", "", "", -4500), ""},
+		{cardTx("ICE.NO MOBIL", "", "", -29900), "Subscriptions"},
+		{cardTx("SAS 1234", "3058", "", -99900), "Travel"},
+		{cardTx("SCANDIC OSLO", "3709", "", -149900), "Travel"},
+		{cardTx("FJORDKRAFT FYRINGSOLJE", "5983", "", -149900), ""},
+		{&sbanken.Transaction{Text: "Lønn", Amount: 2500000}, "Salary"},
+		{&sbanken.Transaction{Text: "Lønn tilbakebetalt", Amount: -2500000}, ""},
+	}
+	e := Default()
+	for _, c := range cases {
+		if got := e.Categorize(c.tx); got != c.expected {
+			t.Errorf("Expected %q for %q, got %q (%s)", c.expected, c.tx.Text, got, e.Explain(c.tx))
+		}
+	}
+}
+
+const testRules = `
+rules:
+  - name: car
+    category: Car
+    contains: [circle k]
+    maxAmount: -500
+  - name: fuel
+    category: Fuel
+    priority: -1
+    mcc: ["5541"]
+  - name: rent
+    category: Housing
+    priority: 5
+    otherAccountNumber: ["9722.19.12345"]
+    transactionTypeCode: [203]
+  - name: coffee
+    category: Coffee
+    priority: 5
+    merchant: [espresso house]
+    minAmount: -100
+`
+
+func TestRulesFromYAML(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	e, err := New(rules)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if first := e.Rules()[0].Name; first != "rent" {
+		t.Errorf("Expected rules to be ordered by priority, got %s first", first)
+	}
+	cases := []struct {
+		tx       *sbanken.Transaction
+		expected string
+	}{
+		{cardTx("CIRCLE K MAJORSTUEN", "5541", "", -80000), "Car"},
+		{cardTx("CIRCLE K MAJORSTUEN", "5541", "", -4000), "Fuel"},
+		{&sbanken.Transaction{Text: "Nettgiro til: Utleier", OtherAccountNumber: "97221912345", TransactionTypeCode: 203, Amount: -1200000}, "Housing"},
+		{&sbanken.Transaction{Text: "Overføring", OtherAccountNumber: "97221912345", TransactionTypeCode: 200, Amount: -1200000}, ""},
+		{cardTx("ESPRESSO HOUSE", "5814", "Espresso House Torgall", -5900), "Coffee"},
+		{cardTx("ESPRESSO HOUSE", "5814", "Espresso House Torgall", -15900), ""},
+	}
+	for _, c := range cases {
+		if got := e.Categorize(c.tx); got != c.expected {
+			t.Errorf("Expected %q for %+v, got %q", c.expected, c.tx, got)
+		}
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "categorize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+	ioutil.WriteFile(path, []byte(`{"rules": [{"name": "big", "category": "Big", "minAmount": "1 000,00"}]}`), 0644)
+	e, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if got := e.Categorize(&sbanken.Transaction{Amount: 100000}); got != "Big" {
+		t.Errorf("Expected Big, got %q", got)
+	}
+	if got := e.Categorize(&sbanken.Transaction{Amount: 99999}); got != "" {
+		t.Errorf("Expected no category, got %q", got)
+	}
+}
+
+// TestDefaultMCCRulesMatchGroups checks that the default rules put
+// merchant category codes in the same group as the MCC table does
+func TestDefaultMCCRulesMatchGroups(t *testing.T) {
+	for _, r := range Default().Rules() {
+		for _, s := range r.MCC {
+			for _, code := range strings.Split(s, "-") {
+				if g := sbanken.ParseMCC(code).Group(); string(g) != r.Category {
+					t.Errorf("Rule %s puts MCC %s in %s, but it is in the group %s", r.Name, code, r.Category, g)
+				}
+			}
+		}
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, r := range []Rule{
+		{Name: "no-category", Contains: []string{"x"}},
+		{Name: "no-conditions", Category: "X"},
+		{Name: "bad-regex", Category: "X", Regex: "("},
+		{Name: "bad-mcc", Category: "X", MCC: []string{"54x1"}},
+		{Name: "bad-mcc-range", Category: "X", MCC: []string{"3299-3000"}},
+	} {
+		if _, err := New([]Rule{r}); err == nil || !strings.Contains(err.Error(), r.Name) {
+			t.Errorf("Expected an error naming %s, got %v", r.Name, err)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	e := Default()
+	ex := e.Explain(cardTx("VINMONOPOLET OSLO", "5921", "", -29900))
+	if ex.Rule == nil || ex.Rule.Name != "vinmonopolet" {
+		t.Fatalf("Expected the vinmonopolet rule to fire, got %+v", ex)
+	}
+	expected := `Alcohol (rule "vinmonopolet", priority 20: text contains "VINMONOPOLET")`
+	if ex.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, ex)
+	}
+	if ex := e.Explain(cardTx("SOMETHING", "", "", -100)); ex.Rule != nil || ex.String() != "no rule matched" {
+		t.Errorf("Expected no rule to match, got %s", ex)
+	}
+}
@@ -0,0 +1,94 @@
+package categorize
+
+// defaultRules are the rules used by Default. Named merchants come
+// first, with merchant category codes as a fallback
+const defaultRules = `
+rules:
+  - name: groceries
+    category: Groceries
+    priority: 20
+    regex: (?i)\b(REMA|KIWI|MENY|COOP|EXTRA|OBS|SPAR|JOKER|BUNNPRIS|NÆRBUTIKKEN|ODA|KOLONIAL)\b
+  - name: vinmonopolet
+    category: Alcohol
+    priority: 20
+    contains: [VINMONOPOLET]
+  - name: public-transport
+    category: Transport
+    priority: 20
+    regex: (?i)\b(NSB|VY|RUTER|SKYSS|ATB|KOLUMBUS|BRAKAR|ENTUR|FLYTOGET|BANE NOR|GO-AHEAD|SJ NORD)\b
+  - name: taxi
+    category: Transport
+    priority: 20
+    regex: (?i)\b(TAXI|DROSJE|UBER|BOLT)\b
+  - name: fuel
+    category: Fuel
+    priority: 20
+    regex: (?i)\b(CIRCLE K|ESSO|SHELL|UNO-X|YX|ST1)\b
+  - name: toll
+    category: Transport
+    priority: 20
+    regex: (?i)\b(AUTOPASS|FJELLINJEN|BOMPENGER|FERDE|EASYPARK|ONEPARK)\b
+  - name: pharmacy
+    category: Health
+    priority: 20
+    regex: (?i)\b(APOTEK|APOTEK 1|VITUSAPOTEK|BOOTS)\b
+  - name: streaming
+    category: Subscriptions
+    priority: 20
+    regex: (?i)\b(NETFLIX|SPOTIFY|HBO|VIAPLAY|TV 2 PLAY|DISNEY PLUS|DISNEYPLUS|TIDAL)\b
+  - name: telecom
+    category: Subscriptions
+    priority: 20
+    regex: (?i)\b(TELENOR|TELIA|ICE\.NO|ICE NORGE|TALKMORE|ALTIBOX|GET AS)\b
+  - name: post
+    category: Shopping
+    priority: 20
+    contains: [POSTEN]
+  - name: salary
+    category: Salary
+    priority: 20
+    regex: (?i)\bLØNN\b
+    minAmount: 0.01
+  - name: groceries-mcc
+    category: Groceries
+    priority: 10
+    mcc: ["5411", "5422", "5441", "5451", "5499"]
+  - name: alcohol-mcc
+    category: Alcohol
+    priority: 10
+    mcc: ["5921"]
+  - name: transport-mcc
+    category: Transport
+    priority: 10
+    mcc: ["4011", "4111", "4112", "4121", "4131", "4784", "7523"]
+  - name: travel-mcc
+    category: Travel
+    priority: 10
+    mcc: ["3000-3299", "3351-3441", "3501-3999", "4411", "4511", "4722", "7011", "7512"]
+  - name: fuel-mcc
+    category: Fuel
+    priority: 10
+    mcc: ["5172", "5541", "5542"]
+  - name: restaurants-mcc
+    category: Restaurants
+    priority: 10
+    mcc: ["5811", "5812", "5813", "5814"]
+  - name: health-mcc
+    category: Health
+    priority: 10
+    mcc: ["5912", "8011", "8021", "8043", "8062"]
+`
+
+// Default returns an Engine with a rule set for Norwegian merchants,
+// like REMA, KIWI, Vinmonopolet and Vy
+func Default() *Engine {
+	rules, err := Parse([]byte(defaultRules))
+	if err != nil {
+		panic(err)
+	}
+	e, err := New(rules)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
@@ -0,0 +1,79 @@
+package sbanken
+
+import (
+	"context"
+	"strings"
+)
+
+const customers = "/Customers"
+
+// Customer is the authenticated customer, as returned from the
+// Customers endpoint
+type Customer struct {
+	CustomerID    string        `json:"customerId"`
+	FirstName     string        `json:"firstName"`
+	LastName      string        `json:"lastName"`
+	EmailAddress  string        `json:"emailAddress"`
+	DateOfBirth   Date          `json:"dateOfBirth"`
+	PostalAddress Address       `json:"postalAddress"`
+	StreetAddress Address       `json:"streetAddress"`
+	PhoneNumbers  []PhoneNumber `json:"phoneNumbers"`
+}
+
+// Address is a postal or street address
+type Address struct {
+	AddressLine1 string `json:"addressLine1"`
+	AddressLine2 string `json:"addressLine2"`
+	AddressLine3 string `json:"addressLine3"`
+	AddressLine4 string `json:"addressLine4"`
+	Country      string `json:"country"`
+	ZipCode      string `json:"zipCode"`
+	City         string `json:"city"`
+}
+
+// PhoneNumber ...
+type PhoneNumber struct {
+	CountryCode string `json:"countryCode"`
+	Number      string `json:"number"`
+}
+
+type customerItemResponse struct {
+	Item Customer `json:"item"`
+	errorInformation
+}
+
+// Name returns the full name of the customer
+func (c Customer) Name() string {
+	return strings.TrimSpace(c.FirstName + " " + c.LastName)
+}
+
+// Lines returns the non-empty lines of the address, ending
+// with the zip code and city
+func (a Address) Lines() []string {
+	var lines []string
+	for _, l := range []string{a.AddressLine1, a.AddressLine2, a.AddressLine3, a.AddressLine4} {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if city := strings.TrimSpace(a.ZipCode + " " + a.City); city != "" {
+		lines = append(lines, city)
+	}
+	return lines
+}
+
+// GetCustomer returns the profile of the authenticated customer
+func (conn *APIConnection) GetCustomer() (Customer, error) {
+	return conn.GetCustomerContext(context.Background())
+}
+
+// GetCustomerContext is like GetCustomer, but the request is bound to ctx
+func (conn *APIConnection) GetCustomerContext(ctx context.Context) (Customer, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(customers)
+	var a customerItemResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return Customer{}, err
+	}
+	return a.Item, nil
+}
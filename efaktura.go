@@ -1,30 +1,39 @@
 package sbanken
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-const newEfakturas = "https://publicapi.sbanken.no/apibeta/api/v1/EFakturas/new"
-const efakturas = "https://publicapi.sbanken.no/apibeta/api/v1/EFakturas"
+const newEfakturas = "/EFakturas/new"
+const efakturas = "/EFakturas"
+
+// eFakturaStatusNew is the status of eFakturas that have not been
+// accepted yet
+const eFakturaStatusNew = "NEW"
 
 // EFaktura as received from the Sbanken public API
 type EFaktura struct {
-	EFakturaID          string  `json:"eFakturaId"`
-	IssuerID            string  `json:"issuerId"`
-	EFakturaReference   string  `json:"eFakturaReference"`
-	DocumentType        string  `json:"documentType"`
-	Status              string  `json:"status"`
-	KID                 string  `json:"kid"`
-	OriginalDueDate     string  `json:"originalDueDate"`
-	OriginalAmount      float64 `json:"originalAmount"`
-	MinimumAmount       float64 `json:"minimumAmount"`
-	NotificationDate    string  `json:"notificationDate"`
-	IssuerName          string  `json:"issuerName"`
-	UpdatedDueDate      string  `json:"updatedDueDate"`
-	UpdatedAmount       float64 `json:"updatedAmount"`
-	CreditAccountNumber string  `json:"creditAccountNumber"`
+	EFakturaID          string `json:"eFakturaId"`
+	IssuerID            string `json:"issuerId"`
+	EFakturaReference   string `json:"eFakturaReference"`
+	DocumentType        string `json:"documentType"`
+	Status              string `json:"status"`
+	KID                 string `json:"kid"`
+	OriginalDueDate     Date   `json:"originalDueDate"`
+	OriginalAmount      Amount `json:"originalAmount"`
+	MinimumAmount       Amount `json:"minimumAmount"`
+	NotificationDate    Date   `json:"notificationDate"`
+	IssuerName          string `json:"issuerName"`
+	UpdatedDueDate      Date   `json:"updatedDueDate"`
+	UpdatedAmount       Amount `json:"updatedAmount"`
+	CreditAccountNumber string `json:"creditAccountNumber"`
 }
 
 // EFakturaPayRequest is used to accept an eFaktura, charging the AccountID
@@ -44,41 +53,140 @@ type eFakturaItemResponse struct {
 	Item EFaktura `json:"item"`
 	errorInformation
 }
+type eFakturaPayResponse struct {
+	errorInformation
+}
 
 // GetNewEFakturas returns eFakturas that has not been accepted yet
 func (conn *APIConnection) GetNewEFakturas() ([]EFaktura, error) {
-	r := newAPIRequest()
-	r.target = newEfakturas
+	return conn.GetNewEFakturasContext(context.Background())
+}
+
+// GetNewEFakturasContext is like GetNewEFakturas, but the request is
+// bound to ctx
+func (conn *APIConnection) GetNewEFakturasContext(ctx context.Context) ([]EFaktura, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(newEfakturas)
 	var a eFakturaListResponse
-	resp, err := conn.makeAPIRequest(r)
-	if err != nil {
+	if err := conn.requestJSON(r, &a); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &a)
 	return a.Items, nil
 }
 
 // GetAllEFakturas returns all pending eFakturas
 func (conn *APIConnection) GetAllEFakturas() ([]EFaktura, error) {
-	r := newAPIRequest()
-	r.target = efakturas
+	return conn.GetAllEFakturasContext(context.Background())
+}
+
+// GetAllEFakturasContext is like GetAllEFakturas, but the request is
+// bound to ctx
+func (conn *APIConnection) GetAllEFakturasContext(ctx context.Context) ([]EFaktura, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(efakturas)
 	var a eFakturaListResponse
 	log.Debug("requesting all efakturas")
-	resp, err := conn.makeAPIRequest(r)
-	log.WithFields(log.Fields{"response": resp})
-	if err != nil {
+	if err := conn.requestJSON(r, &a); err != nil {
+		return nil, err
+	}
+	return a.Items, nil
+}
+
+// EFakturaFilter limits the eFakturas returned by ListEFakturas.
+// Zero values are left out of the query
+type EFakturaFilter struct {
+	// Status is one of the eFaktura statuses, like NEW or PROCESSED
+	Status string
+	// StartDate and EndDate are taken as the calendar day they fall on
+	// in Oslo, whatever their location
+	StartDate time.Time
+	EndDate   time.Time
+	Index     int
+	Length    int
+}
+
+// ListEFakturas returns the eFakturas matching filter
+func (conn *APIConnection) ListEFakturas(ctx context.Context, filter EFakturaFilter) ([]EFaktura, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(efakturas)
+	if filter.Status != "" {
+		r.params["status"] = filter.Status
+	}
+	if !filter.StartDate.IsZero() {
+		r.params["startDate"] = osloDay(filter.StartDate).Format(queryDateFormat)
+	}
+	if !filter.EndDate.IsZero() {
+		r.params["endDate"] = osloDay(filter.EndDate).Format(queryDateFormat)
+	}
+	if filter.Index > 0 {
+		r.params["index"] = strconv.Itoa(filter.Index)
+	}
+	if filter.Length > 0 {
+		r.params["length"] = strconv.Itoa(filter.Length)
+	}
+	var a eFakturaListResponse
+	if err := conn.requestJSON(r, &a); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &a)
 	return a.Items, nil
 }
 
-// GetEFaktura returns information on a single EFaktura specified by eFakturaID
-func (conn *APIConnection) GetEFaktura(eFakturaID string) EFaktura {
-	r := newAPIRequest()
-	r.target = efakturas + "/" + eFakturaID
+// GetEFaktura returns information on a single EFaktura specified by eFakturaID.
+// The returned error matches ErrNotFound if there is no such eFaktura
+func (conn *APIConnection) GetEFaktura(eFakturaID string) (EFaktura, error) {
+	return conn.GetEFakturaContext(context.Background(), eFakturaID)
+}
+
+// GetEFakturaContext is like GetEFaktura, but the request is bound to ctx
+func (conn *APIConnection) GetEFakturaContext(ctx context.Context, eFakturaID string) (EFaktura, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(efakturas + "/" + eFakturaID)
 	var a eFakturaItemResponse
-	resp, _ := conn.makeAPIRequest(r)
-	json.Unmarshal(resp, &a)
-	return a.Item
+	if err := conn.requestJSON(r, &a); err != nil {
+		return EFaktura{}, err
+	}
+	if a.Item.EFakturaID == "" {
+		return EFaktura{}, fmt.Errorf("eFaktura %s was not found: %w", eFakturaID, ErrNotFound)
+	}
+	return a.Item, nil
+}
+
+// PayEFaktura accepts an eFaktura, so that it will be paid from
+// AccountID on the due date. The eFaktura must exist and still be new,
+// and if PayOnlyMinimumAmount is set it must have a minimum amount.
+// Rejections from Sbanken are returned as an *APIError
+func (conn *APIConnection) PayEFaktura(ctx context.Context, pay EFakturaPayRequest) error {
+	if pay.EFakturaID == "" || pay.AccountID == "" {
+		return fmt.Errorf("Both the eFaktura and the account to pay from must be given: %w", ErrValidation)
+	}
+	e, err := conn.GetEFakturaContext(ctx, pay.EFakturaID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(e.Status, eFakturaStatusNew) {
+		return fmt.Errorf("eFaktura %s can not be paid, its status is %q: %w", e.EFakturaID, e.Status, ErrValidation)
+	}
+	if pay.PayOnlyMinimumAmount {
+		if e.MinimumAmount <= 0 {
+			return fmt.Errorf("eFaktura %s has no minimum amount: %w", e.EFakturaID, ErrValidation)
+		}
+		if e.MinimumAmount > e.payableAmount() {
+			return fmt.Errorf("eFaktura %s has a minimum amount larger than the amount due: %w", e.EFakturaID, ErrValidation)
+		}
+	}
+	r := newAPIRequest(ctx)
+	r.method = http.MethodPost
+	r.target = conn.endpoint(efakturas)
+	r.body = pay
+	var a eFakturaPayResponse
+	return conn.requestAction(r, &a)
+}
+
+// payableAmount returns the amount currently due, taking updates
+// from the issuer into account
+func (e EFaktura) payableAmount() Amount {
+	if e.UpdatedAmount != 0 {
+		return e.UpdatedAmount
+	}
+	return e.OriginalAmount
 }
@@ -0,0 +1,141 @@
+package sbanken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors that can be matched against errors returned from
+// the APIConnection methods using errors.Is
+var (
+	ErrUnauthorized = errors.New("sbanken: unauthorized")
+	ErrNotFound     = errors.New("sbanken: not found")
+	ErrRateLimited  = errors.New("sbanken: rate limited")
+	ErrValidation   = errors.New("sbanken: validation failed")
+)
+
+// APIError is returned when Sbanken rejects a request, either with
+// an HTTP error status or with isError set in the response body.
+// StatusCode is 0 for errors reported in the body of a successful
+// response. Quote the TraceID when contacting Sbanken support.
+type APIError struct {
+	StatusCode int
+	ErrorType  string
+	Message    string
+	TraceID    string
+
+	target string
+	kind   error
+}
+
+func (e *APIError) Error() string {
+	msg := "Sbanken reported an error"
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("Got \"%d %s\"", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.target != "" {
+		msg += " while requesting " + e.target
+	}
+	if e.ErrorType != "" {
+		msg += " (" + e.ErrorType + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.TraceID != "" {
+		msg += " [traceId " + e.TraceID + "]"
+	}
+	return msg
+}
+
+// Is makes it possible to match an APIError against the sentinel
+// errors ErrUnauthorized, ErrNotFound, ErrRateLimited and ErrValidation
+func (e *APIError) Is(target error) bool {
+	if e.kind != nil {
+		return e.kind == target
+	}
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest || e.ErrorType == "Input"
+	}
+	return false
+}
+
+// newHTTPError builds an APIError from a failed response, picking up
+// the errorInformation from the body when Sbanken supplied one
+func newHTTPError(status int, target string, body []byte) *APIError {
+	var info errorInformation
+	json.Unmarshal(body, &info)
+	return &APIError{
+		StatusCode: status,
+		ErrorType:  info.ErrorType,
+		Message:    info.ErrorMessage,
+		TraceID:    info.TraceID,
+		target:     target,
+	}
+}
+
+// apiError returns an APIError if Sbanken flagged the response as failed
+func (e errorInformation) apiError() error {
+	if !e.IsError {
+		return nil
+	}
+	return &APIError{
+		ErrorType: e.ErrorType,
+		Message:   e.ErrorMessage,
+		TraceID:   e.TraceID,
+	}
+}
+
+type apiResponse interface {
+	apiError() error
+}
+
+// requestJSON performs the request and decodes the response into v,
+// returning an error if the request failed, the response was empty or
+// could not be decoded, or Sbanken reported an error in the response
+// body
+func (conn *APIConnection) requestJSON(r apirequest, v apiResponse) error {
+	resp, err := conn.makeAPIRequest(r)
+	if err != nil {
+		return err
+	}
+	if len(resp) == 0 {
+		return fmt.Errorf("Got an empty response from %s", r.target)
+	}
+	return decodeJSON(r, resp, v)
+}
+
+// requestAction is like requestJSON, but for requests that change
+// something, where Sbanken may answer with an empty body
+func (conn *APIConnection) requestAction(r apirequest, v apiResponse) error {
+	resp, err := conn.makeAPIRequest(r)
+	if err != nil {
+		return err
+	}
+	if len(resp) == 0 {
+		return nil
+	}
+	return decodeJSON(r, resp, v)
+}
+
+func decodeJSON(r apirequest, resp []byte, v apiResponse) error {
+	if err := json.Unmarshal(resp, v); err != nil {
+		return fmt.Errorf("Failed to decode response from %s: %w", r.target, err)
+	}
+	if err := v.apiError(); err != nil {
+		if apiErr, ok := err.(*APIError); ok {
+			apiErr.target = r.target
+		}
+		return err
+	}
+	return nil
+}
@@ -29,7 +29,7 @@ func main() {
 	fmt.Println("╔══ Account overview ══════════════════════════════════════════════╗")
 	fmt.Printf("║ %-25s%11s    % 10s    % 10s ║\n", "Name", "Number", "Balance", "Available")
 	for _, acc := range accounts {
-		fmt.Printf("║ %-25s%11s kr % 10.2f kr % 10.2f ║\n", acc.Name, acc.AccountNumber, acc.Balance, acc.Available)
+		fmt.Printf("║ %-25s%11s kr % 10.2f kr % 10.2f ║\n", acc.Name, acc.AccountNumber, acc.Balance.Float64(), acc.Available.Float64())
 	}
 
 	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
@@ -0,0 +1,333 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// camt053Namespace is the namespace of ISO 20022 camt.053.001.02
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// sbankenBIC is the BIC of Sbanken, used as the account servicer
+const sbankenBIC = "SBAKNOBB"
+
+const (
+	camtDateFormat     = "2006-01-02"
+	camtDateTimeFormat = "2006-01-02T15:04:05"
+	// camtMax35 and camtMax140 are the lengths of Max35Text and
+	// Max140Text in the schema
+	camtMax35  = 35
+	camtMax140 = 140
+)
+
+// kidPattern finds a KID in a transaction text, like "KID: 12345678903"
+var kidPattern = regexp.MustCompile(`(?i)\bKID\b[:.]?\s*([0-9]{2,24}[0-9-])`)
+
+// CamtOptions adjusts the statement written by WriteCamt053
+type CamtOptions struct {
+	// From and To are the first and last day of the statement. They
+	// default to the first and last accounting date in the transactions
+	From time.Time
+	To   time.Time
+	// Created is the creation time of the statement, and defaults to now
+	Created time.Time
+	// IncludeReservations adds reserved transactions as entries with
+	// the status PDNG. They are left out of the balances and of the
+	// summary
+	IncludeReservations bool
+	// KID returns the KID of a transaction. It defaults to looking for
+	// a valid KID in the transaction text
+	KID func(tx *sbanken.Transaction) string
+}
+
+type camtDocument struct {
+	XMLName   xml.Name      `xml:"Document"`
+	Namespace string        `xml:"xmlns,attr"`
+	Statement camtStatement `xml:"BkToCstmrStmt"`
+}
+
+type camtStatement struct {
+	GroupHeader struct {
+		MessageID string `xml:"MsgId"`
+		Created   string `xml:"CreDtTm"`
+	} `xml:"GrpHdr"`
+	Statement struct {
+		ID      string `xml:"Id"`
+		Created string `xml:"CreDtTm"`
+		FromTo  struct {
+			From string `xml:"FrDtTm"`
+			To   string `xml:"ToDtTm"`
+		} `xml:"FrToDt"`
+		Account  camtAccount   `xml:"Acct"`
+		Balances []camtBalance `xml:"Bal"`
+		Summary  *camtSummary  `xml:"TxsSummry,omitempty"`
+		Entries  []camtEntry   `xml:"Ntry"`
+	} `xml:"Stmt"`
+}
+
+type camtAccount struct {
+	ID       camtAccountID `xml:"Id"`
+	Currency string        `xml:"Ccy"`
+	Name     string        `xml:"Nm,omitempty"`
+	Servicer struct {
+		BIC string `xml:"FinInstnId>BIC"`
+	} `xml:"Svcr"`
+}
+
+type camtAccountID struct {
+	Other struct {
+		ID     string `xml:"Id"`
+		Scheme string `xml:"SchmeNm>Cd,omitempty"`
+	} `xml:"Othr"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type   string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount `xml:"Amt"`
+	CdtDbt string     `xml:"CdtDbtInd"`
+	Date   string     `xml:"Dt>Dt"`
+}
+
+type camtDate struct {
+	Date string `xml:"Dt"`
+}
+
+type camtSummary struct {
+	Entries int    `xml:"TtlNtries>NbOfNtries"`
+	Sum     string `xml:"TtlNtries>Sum"`
+	Net     string `xml:"TtlNtries>TtlNetNtryAmt"`
+	CdtDbt  string `xml:"TtlNtries>CdtDbtInd"`
+}
+
+type camtEntry struct {
+	Reference   string     `xml:"NtryRef,omitempty"`
+	Amount      camtAmount `xml:"Amt"`
+	CdtDbt      string     `xml:"CdtDbtInd"`
+	Status      string     `xml:"Sts"`
+	BookingDate string     `xml:"BookgDt>Dt"`
+	ValueDate   *camtDate  `xml:"ValDt,omitempty"`
+	ServicerRef string     `xml:"AcctSvcrRef,omitempty"`
+	BankCode    struct {
+		Code   string `xml:"Cd"`
+		Issuer string `xml:"Issr"`
+	} `xml:"BkTxCd>Prtry"`
+	Details        camtTransactionDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string                 `xml:"AddtlNtryInf,omitempty"`
+}
+
+type camtTransactionDetails struct {
+	Refs       *camtRefs           `xml:"Refs,omitempty"`
+	Parties    *camtRelatedParties `xml:"RltdPties,omitempty"`
+	Remittance struct {
+		Unstructured string              `xml:"Ustrd,omitempty"`
+		Structured   *camtStructuredInfo `xml:"Strd,omitempty"`
+	} `xml:"RmtInf"`
+	AdditionalInfo string `xml:"AddtlTxInf,omitempty"`
+}
+
+type camtRefs struct {
+	ServicerRef string `xml:"AcctSvcrRef"`
+}
+
+type camtRelatedParties struct {
+	DebtorAccount   *camtAccountID `xml:"DbtrAcct>Id,omitempty"`
+	CreditorAccount *camtAccountID `xml:"CdtrAcct>Id,omitempty"`
+}
+
+type camtStructuredInfo struct {
+	Type      string `xml:"CdtrRefInf>Tp>CdOrPrtry>Cd"`
+	Reference string `xml:"CdtrRefInf>Ref"`
+}
+
+// WriteCamt053 writes an ISO 20022 camt.053.001.02 bank statement for
+// account, with the booked transactions in txs that have an accounting
+// date between From and To.
+//
+// The closing balance is worked out from Account.Balance by rolling
+// back the transactions booked after To, so txs should include all
+// transactions booked after the statement period. The opening balance
+// is the closing balance less the entries of the statement.
+//
+// Entries with a transaction ID from Sbanken use it as their NtryRef
+// and AcctSvcrRef, see TransactionIDs. The other entries have no
+// reference, since the ID worked out for them is too long for camt
+func WriteCamt053(w io.Writer, account sbanken.Account, txs []sbanken.Transaction, opts CamtOptions) error {
+	from, to := camtDay(opts.From), camtDay(opts.To)
+	if from.IsZero() || to.IsZero() {
+		for i := range txs {
+			d := camtDay(txs[i].GetAccountingDate())
+			if opts.From.IsZero() && (from.IsZero() || d.Before(from)) {
+				from = d
+			}
+			if opts.To.IsZero() && d.After(to) {
+				to = d
+			}
+		}
+	}
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return fmt.Errorf("Invalid statement period %s - %s", from.Format(camtDateFormat), to.Format(camtDateFormat))
+	}
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	created = created.In(sbanken.Oslo)
+	kid := opts.KID
+	if kid == nil {
+		kid = TransactionKID
+	}
+	number := sbanken.NormalizeAccountNumber(account.AccountNumber)
+
+	doc := camtDocument{Namespace: camt053Namespace}
+	doc.Statement.GroupHeader.MessageID = truncate(fmt.Sprintf("%s-%s", number, created.Format("20060102150405")), camtMax35)
+	doc.Statement.GroupHeader.Created = created.Format(camtDateTimeFormat)
+	st := &doc.Statement.Statement
+	st.ID = truncate(fmt.Sprintf("%s-%s-%s", number, from.Format("20060102"), to.Format("20060102")), camtMax35)
+	st.Created = created.Format(camtDateTimeFormat)
+	st.FromTo.From = from.Format(camtDateTimeFormat)
+	st.FromTo.To = to.Add(24*time.Hour - time.Second).Format(camtDateTimeFormat)
+	st.Account.ID = camtAccountNumber(number)
+	st.Account.Currency = "NOK"
+	st.Account.Name = truncate(account.Name, camtMax35)
+	st.Account.Servicer.BIC = sbankenBIC
+
+	closing := account.Balance
+	var net, sum sbanken.Amount
+	booked := 0
+	ids := TransactionIDs(txs)
+	for i := range txs {
+		tx := &txs[i]
+		d := camtDay(tx.GetAccountingDate())
+		if !tx.IsReservation && d.After(to) {
+			closing = closing.Sub(tx.Amount)
+			continue
+		}
+		if d.Before(from) || d.After(to) || (tx.IsReservation && !opts.IncludeReservations) {
+			continue
+		}
+		st.Entries = append(st.Entries, camtNewEntry(tx, ids[i], kid(tx)))
+		if !tx.IsReservation {
+			booked++
+			net = net.Add(tx.Amount)
+			sum = sum.Add(tx.Amount.Abs())
+		}
+	}
+	opening := closing.Sub(net)
+	st.Balances = []camtBalance{
+		camtNewBalance("OPBD", opening, from),
+		camtNewBalance("CLBD", closing, to),
+	}
+	st.Summary = &camtSummary{
+		Entries: booked,
+		Sum:     sum.Decimal(),
+		Net:     net.Abs().Decimal(),
+		CdtDbt:  camtCreditDebit(net),
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("Failed to write camt.053: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// TransactionKID returns the KID in the text of tx, or an empty string
+// if there is no valid KID
+func TransactionKID(tx *sbanken.Transaction) string {
+	for _, m := range kidPattern.FindAllStringSubmatch(tx.Text, -1) {
+		if sbanken.ValidateKID(m[1]) == nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// camtNewEntry returns the entry for tx. The id is only used as a
+// reference when it comes from Sbanken and fits in Max35Text, as a cut
+// off PurchaseID would no longer identify the transaction
+func camtNewEntry(tx *sbanken.Transaction, id string, kid string) camtEntry {
+	e := camtEntry{
+		Amount:      camtAmount{Currency: "NOK", Value: tx.Amount.Abs().Decimal()},
+		CdtDbt:      camtCreditDebit(tx.Amount),
+		Status:      "BOOK",
+		BookingDate: tx.GetAccountingDate().In(sbanken.Oslo).Format(camtDateFormat),
+	}
+	if tx.IsReservation {
+		e.Status = "PDNG"
+	}
+	if !tx.InterestDate.IsZero() {
+		e.ValueDate = &camtDate{tx.GetInterestDate().In(sbanken.Oslo).Format(camtDateFormat)}
+	}
+	if tx.TransactionID != "" && utf8.RuneCountInString(id) <= camtMax35 {
+		e.Reference = id
+		e.ServicerRef = id
+		e.Details.Refs = &camtRefs{ServicerRef: id}
+	}
+	e.BankCode.Code = fmt.Sprint(tx.TransactionTypeCode)
+	e.BankCode.Issuer = "Sbanken"
+	if tx.OtherAccountNumber != "" {
+		other := camtAccountNumber(sbanken.NormalizeAccountNumber(tx.OtherAccountNumber))
+		if tx.Amount.Sign() >= 0 {
+			e.Details.Parties = &camtRelatedParties{DebtorAccount: &other}
+		} else {
+			e.Details.Parties = &camtRelatedParties{CreditorAccount: &other}
+		}
+	}
+	e.Details.Remittance.Unstructured = truncate(tx.GetText(), camtMax140)
+	if kid != "" {
+		e.Details.Remittance.Structured = &camtStructuredInfo{Type: "SCOR", Reference: kid}
+	}
+	e.Details.AdditionalInfo = truncate(tx.TransactionTypeText, camtMax140)
+	e.AdditionalInfo = truncate(tx.Text, camtMax140)
+	return e
+}
+
+func camtNewBalance(code string, amount sbanken.Amount, date time.Time) camtBalance {
+	return camtBalance{
+		Type:   code,
+		Amount: camtAmount{Currency: "NOK", Value: amount.Abs().Decimal()},
+		CdtDbt: camtCreditDebit(amount),
+		Date:   date.Format(camtDateFormat),
+	}
+}
+
+func camtAccountNumber(number string) camtAccountID {
+	var id camtAccountID
+	id.Other.ID = number
+	id.Other.Scheme = "BBAN"
+	return id
+}
+
+// camtCreditDebit returns the credit/debit indicator of amount. Zero
+// amounts are credits
+func camtCreditDebit(amount sbanken.Amount) string {
+	if amount.Sign() < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+// camtDay returns the start of the day t is in, in Norwegian time
+func camtDay(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	t = t.In(sbanken.Oslo)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, sbanken.Oslo)
+}
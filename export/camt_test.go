@@ -0,0 +1,477 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// particle is an element in a complex type of the camt.053.001.02
+// schema. A max of 0 means unbounded
+type particle struct {
+	name, typ string
+	min, max  int
+}
+
+// camtSchema is the part of camt.053.001.02 that WriteCamt053 uses,
+// with element order and cardinality as in the XSD
+var camtSchema = map[string][]particle{
+	"Document": {{"BkToCstmrStmt", "BankToCustomerStatementV02", 1, 1}},
+	"BankToCustomerStatementV02": {
+		{"GrpHdr", "GroupHeader42", 1, 1},
+		{"Stmt", "AccountStatement2", 1, 0},
+	},
+	"GroupHeader42": {
+		{"MsgId", "Max35Text", 1, 1},
+		{"CreDtTm", "ISODateTime", 1, 1},
+		{"AddtlInf", "Max500Text", 0, 1},
+	},
+	"AccountStatement2": {
+		{"Id", "Max35Text", 1, 1},
+		{"ElctrncSeqNb", "DecimalNumber", 0, 1},
+		{"LglSeqNb", "DecimalNumber", 0, 1},
+		{"CreDtTm", "ISODateTime", 1, 1},
+		{"FrToDt", "DateTimePeriodDetails", 0, 1},
+		{"Acct", "CashAccount20", 1, 1},
+		{"Bal", "CashBalance3", 1, 0},
+		{"TxsSummry", "TotalTransactions2", 0, 1},
+		{"Ntry", "ReportEntry2", 0, 0},
+		{"AddtlStmtInf", "Max500Text", 0, 1},
+	},
+	"DateTimePeriodDetails": {
+		{"FrDtTm", "ISODateTime", 1, 1},
+		{"ToDtTm", "ISODateTime", 1, 1},
+	},
+	"CashAccount20": {
+		{"Id", "AccountIdentification4Choice", 1, 1},
+		{"Ccy", "ActiveOrHistoricCurrencyCode", 0, 1},
+		{"Nm", "Max70Text", 0, 1},
+		{"Svcr", "BranchAndFinancialInstitutionIdentification4", 0, 1},
+	},
+	"CashAccount16": {
+		{"Id", "AccountIdentification4Choice", 1, 1},
+		{"Ccy", "ActiveOrHistoricCurrencyCode", 0, 1},
+		{"Nm", "Max70Text", 0, 1},
+	},
+	"AccountIdentification4Choice": {
+		{"IBAN", "Max34Text", 0, 1},
+		{"Othr", "GenericAccountIdentification1", 0, 1},
+	},
+	"GenericAccountIdentification1": {
+		{"Id", "Max34Text", 1, 1},
+		{"SchmeNm", "AccountSchemeName1Choice", 0, 1},
+		{"Issr", "Max35Text", 0, 1},
+	},
+	"AccountSchemeName1Choice": {
+		{"Cd", "ExternalAccountIdentification1Code", 0, 1},
+		{"Prtry", "Max35Text", 0, 1},
+	},
+	"BranchAndFinancialInstitutionIdentification4": {
+		{"FinInstnId", "FinancialInstitutionIdentification7", 1, 1},
+	},
+	"FinancialInstitutionIdentification7": {
+		{"BIC", "BICIdentifier", 0, 1},
+		{"Nm", "Max140Text", 0, 1},
+	},
+	"CashBalance3": {
+		{"Tp", "BalanceType12", 1, 1},
+		{"Amt", "ActiveOrHistoricCurrencyAndAmount", 1, 1},
+		{"CdtDbtInd", "CreditDebitCode", 1, 1},
+		{"Dt", "DateAndDateTimeChoice", 1, 1},
+	},
+	"BalanceType12": {
+		{"CdOrPrtry", "BalanceType5Choice", 1, 1},
+	},
+	"BalanceType5Choice": {
+		{"Cd", "BalanceType12Code", 0, 1},
+		{"Prtry", "Max35Text", 0, 1},
+	},
+	"DateAndDateTimeChoice": {
+		{"Dt", "ISODate", 0, 1},
+		{"DtTm", "ISODateTime", 0, 1},
+	},
+	"TotalTransactions2": {
+		{"TtlNtries", "NumberAndSumOfTransactions2", 0, 1},
+	},
+	"NumberAndSumOfTransactions2": {
+		{"NbOfNtries", "Max15NumericText", 0, 1},
+		{"Sum", "DecimalNumber", 0, 1},
+		{"TtlNetNtryAmt", "DecimalNumber", 0, 1},
+		{"CdtDbtInd", "CreditDebitCode", 0, 1},
+	},
+	"ReportEntry2": {
+		{"NtryRef", "Max35Text", 0, 1},
+		{"Amt", "ActiveOrHistoricCurrencyAndAmount", 1, 1},
+		{"CdtDbtInd", "CreditDebitCode", 1, 1},
+		{"RvslInd", "TrueFalseIndicator", 0, 1},
+		{"Sts", "EntryStatus2Code", 1, 1},
+		{"BookgDt", "DateAndDateTimeChoice", 0, 1},
+		{"ValDt", "DateAndDateTimeChoice", 0, 1},
+		{"AcctSvcrRef", "Max35Text", 0, 1},
+		{"BkTxCd", "BankTransactionCodeStructure4", 1, 1},
+		{"NtryDtls", "EntryDetails1", 0, 0},
+		{"AddtlNtryInf", "Max500Text", 0, 1},
+	},
+	"BankTransactionCodeStructure4": {
+		{"Prtry", "ProprietaryBankTransactionCodeStructure1", 0, 1},
+	},
+	"ProprietaryBankTransactionCodeStructure1": {
+		{"Cd", "Max35Text", 1, 1},
+		{"Issr", "Max35Text", 0, 1},
+	},
+	"EntryDetails1": {
+		{"TxDtls", "EntryTransaction2", 0, 0},
+	},
+	"EntryTransaction2": {
+		{"Refs", "TransactionReferences2", 0, 1},
+		{"RltdPties", "TransactionParty2", 0, 1},
+		{"RmtInf", "RemittanceInformation5", 0, 1},
+		{"AddtlTxInf", "Max500Text", 0, 1},
+	},
+	"TransactionReferences2": {
+		{"MsgId", "Max35Text", 0, 1},
+		{"AcctSvcrRef", "Max35Text", 0, 1},
+		{"EndToEndId", "Max35Text", 0, 1},
+	},
+	"TransactionParty2": {
+		{"DbtrAcct", "CashAccount16", 0, 1},
+		{"CdtrAcct", "CashAccount16", 0, 1},
+	},
+	"RemittanceInformation5": {
+		{"Ustrd", "Max140Text", 0, 0},
+		{"Strd", "StructuredRemittanceInformation7", 0, 0},
+	},
+	"StructuredRemittanceInformation7": {
+		{"CdtrRefInf", "CreditorReferenceInformation2", 0, 1},
+	},
+	"CreditorReferenceInformation2": {
+		{"Tp", "CreditorReferenceType2", 0, 1},
+		{"Ref", "Max35Text", 0, 1},
+	},
+	"CreditorReferenceType2": {
+		{"CdOrPrtry", "CreditorReferenceType1Choice", 1, 1},
+		{"Issr", "Max35Text", 0, 1},
+	},
+	"CreditorReferenceType1Choice": {
+		{"Cd", "DocumentType3Code", 0, 1},
+		{"Prtry", "Max35Text", 0, 1},
+	},
+}
+
+// camtChoices are the types where exactly one of the elements is used
+var camtChoices = map[string]bool{
+	"AccountIdentification4Choice": true,
+	"AccountSchemeName1Choice":     true,
+	"BalanceType5Choice":           true,
+	"DateAndDateTimeChoice":        true,
+	"CreditorReferenceType1Choice": true,
+}
+
+func maxText(n int) func(string) bool {
+	return func(s string) bool { return len([]rune(s)) >= 1 && len([]rune(s)) <= n }
+}
+
+func pattern(p string) func(string) bool {
+	return regexp.MustCompile(p).MatchString
+}
+
+func layout(l string) func(string) bool {
+	return func(s string) bool { _, err := time.Parse(l, s); return err == nil }
+}
+
+var camtSimpleTypes = map[string]func(string) bool{
+	"Max34Text":                          maxText(34),
+	"Max35Text":                          maxText(35),
+	"Max70Text":                          maxText(70),
+	"Max140Text":                         maxText(140),
+	"Max500Text":                         maxText(500),
+	"ExternalAccountIdentification1Code": maxText(4),
+	"Max15NumericText":                   pattern(`^[0-9]{1,15}$`),
+	"DecimalNumber":                      pattern(`^-?[0-9]{1,17}(\.[0-9]{1,17})?$`),
+	"ActiveOrHistoricCurrencyAndAmount":  pattern(`^[0-9]{1,13}(\.[0-9]{1,5})?$`),
+	"ActiveOrHistoricCurrencyCode":       pattern(`^[A-Z]{3}$`),
+	"BICIdentifier":                      pattern(`^[A-Z]{6}[A-Z2-9][A-NP-Z0-9]([A-Z0-9]{3})?$`),
+	"CreditDebitCode":                    pattern(`^(CRDT|DBIT)$`),
+	"EntryStatus2Code":                   pattern(`^(BOOK|PDNG|INFO)$`),
+	"BalanceType12Code":                  pattern(`^(XPCD|OPAV|ITAV|CLAV|FWAV|CLBD|ITBD|OPBD|PRCD|INFO)$`),
+	"DocumentType3Code":                  pattern(`^(RADM|RPIN|FXDR|DISP|PUOR|SCOR)$`),
+	"TrueFalseIndicator":                 pattern(`^(true|false)$`),
+	"ISODate":                            layout("2006-01-02"),
+	"ISODateTime":                        layout("2006-01-02T15:04:05"),
+}
+
+type xmlNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     string
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+}
+
+// validateCamt checks the document against camtSchema, returning the
+// problems found
+func validateCamt(root *xmlNode) []string {
+	var problems []string
+	if root.name.Space != camt053Namespace || root.name.Local != "Document" {
+		problems = append(problems, fmt.Sprintf("unexpected root element %v", root.name))
+	}
+	validateNode(root, "Document", "/Document", &problems)
+	return problems
+}
+
+func validateNode(n *xmlNode, typ, path string, problems *[]string) {
+	if n.name.Space != camt053Namespace {
+		*problems = append(*problems, fmt.Sprintf("%s: wrong namespace %q", path, n.name.Space))
+	}
+	if check, ok := camtSimpleTypes[typ]; ok {
+		if len(n.children) > 0 {
+			*problems = append(*problems, fmt.Sprintf("%s: %s can not have child elements", path, typ))
+		}
+		if !check(n.text) {
+			*problems = append(*problems, fmt.Sprintf("%s: %q is not a valid %s", path, n.text, typ))
+		}
+		if typ == "ActiveOrHistoricCurrencyAndAmount" {
+			ccy := ""
+			for _, a := range n.attrs {
+				if a.Name.Local == "Ccy" {
+					ccy = a.Value
+				}
+			}
+			if !camtSimpleTypes["ActiveOrHistoricCurrencyCode"](ccy) {
+				*problems = append(*problems, fmt.Sprintf("%s: missing or invalid Ccy %q", path, ccy))
+			}
+		}
+		return
+	}
+	particles, ok := camtSchema[typ]
+	if !ok {
+		*problems = append(*problems, fmt.Sprintf("%s: unknown type %s", path, typ))
+		return
+	}
+	if strings.TrimSpace(n.text) != "" {
+		*problems = append(*problems, fmt.Sprintf("%s: unexpected text %q", path, n.text))
+	}
+	if camtChoices[typ] && len(n.children) != 1 {
+		*problems = append(*problems, fmt.Sprintf("%s: expected one choice, got %d elements", path, len(n.children)))
+	}
+	p, count := 0, 0
+	for _, c := range n.children {
+		for p < len(particles) && particles[p].name != c.name.Local {
+			if count < particles[p].min {
+				*problems = append(*problems, fmt.Sprintf("%s: missing %s", path, particles[p].name))
+			}
+			p, count = p+1, 0
+		}
+		if p == len(particles) {
+			*problems = append(*problems, fmt.Sprintf("%s: unexpected or misplaced element %s", path, c.name.Local))
+			return
+		}
+		count++
+		if particles[p].max > 0 && count > particles[p].max {
+			*problems = append(*problems, fmt.Sprintf("%s: too many %s", path, c.name.Local))
+		}
+		validateNode(c, particles[p].typ, path+"/"+c.name.Local, problems)
+	}
+	for ; p < len(particles); p, count = p+1, 0 {
+		if count < particles[p].min {
+			*problems = append(*problems, fmt.Sprintf("%s: missing %s", path, particles[p].name))
+		}
+	}
+}
+
+func camtTestTxs() []sbanken.Transaction {
+	txs := append([]sbanken.Transaction{}, testTxs...)
+	txs[0].InterestDate = date("2019-10-24T00:00:00")
+	return append(txs, sbanken.Transaction{
+		TransactionID: "T2", Amount: -45000, AccountingDate: date("2019-10-24T00:00:00"),
+		Text: "Nettgiro til: Strøm AS KID: 12345678903", TransactionTypeText: "NETTGIRO", TransactionTypeCode: 203,
+		OtherAccountNumber: "9722.19.12345",
+	})
+}
+
+func writeTestCamt(t *testing.T, opts CamtOptions) (*bytes.Buffer, *xmlNode) {
+	var buf bytes.Buffer
+	if opts.Created.IsZero() {
+		opts.Created = time.Date(2019, 10, 27, 8, 0, 0, 0, sbanken.Oslo)
+	}
+	if err := WriteCamt053(&buf, testAccount, camtTestTxs(), opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	root, err := parseXML(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	return &buf, root
+}
+
+func TestCamt053Schema(t *testing.T) {
+	for _, opts := range []CamtOptions{
+		{},
+		{IncludeReservations: true},
+		{From: time.Date(2019, 10, 23, 0, 0, 0, 0, sbanken.Oslo), To: time.Date(2019, 10, 24, 0, 0, 0, 0, sbanken.Oslo)},
+	} {
+		buf, root := writeTestCamt(t, opts)
+		for _, p := range validateCamt(root) {
+			t.Errorf("%+v: %s", opts, p)
+		}
+		if t.Failed() {
+			t.Log(buf.String())
+		}
+	}
+}
+
+func TestCamt053SchemaCatchesErrors(t *testing.T) {
+	doc := `<Document xmlns="` + camt053Namespace + `"><BkToCstmrStmt>
+<GrpHdr><CreDtTm>2019-10-27T08:00:00</CreDtTm><MsgId>1</MsgId></GrpHdr>
+</BkToCstmrStmt></Document>`
+	root, err := parseXML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := validateCamt(root); len(problems) < 2 {
+		t.Errorf("Expected misplaced MsgId and missing Stmt to be reported, got %v", problems)
+	}
+}
+
+func TestCamt053Content(t *testing.T) {
+	var doc camtDocument
+	buf, _ := writeTestCamt(t, CamtOptions{
+		From: time.Date(2019, 10, 23, 0, 0, 0, 0, sbanken.Oslo),
+		To:   time.Date(2019, 10, 24, 0, 0, 0, 0, sbanken.Oslo),
+	})
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	st := doc.Statement.Statement
+	// The salary is booked after the period, so the closing balance is
+	// 1234.56 - 25000.00
+	expectedBalances := []camtBalance{
+		{Type: "OPBD", Amount: camtAmount{"NOK", "23299.03"}, CdtDbt: "DBIT", Date: "2019-10-23"},
+		{Type: "CLBD", Amount: camtAmount{"NOK", "23765.44"}, CdtDbt: "DBIT", Date: "2019-10-24"},
+	}
+	for i, b := range expectedBalances {
+		if i >= len(st.Balances) || st.Balances[i] != b {
+			t.Errorf("Expected balance %+v, got %+v", b, st.Balances)
+		}
+	}
+	if len(st.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(st.Entries))
+	}
+	first := st.Entries[0]
+	if first.Reference != "T1" || first.CdtDbt != "DBIT" || first.Amount.Value != "16.41" ||
+		first.BookingDate != "2019-10-23" || first.ValueDate == nil || first.ValueDate.Date != "2019-10-24" || first.Status != "BOOK" {
+		t.Errorf("Unexpected entry %+v", first)
+	}
+	second := st.Entries[1]
+	if second.Details.Remittance.Structured == nil || second.Details.Remittance.Structured.Reference != "12345678903" {
+		t.Errorf("Expected the KID as a structured reference, got %+v", second.Details.Remittance)
+	}
+	if second.Details.Parties == nil || second.Details.Parties.CreditorAccount == nil ||
+		second.Details.Parties.CreditorAccount.Other.ID != "97221912345" {
+		t.Errorf("Expected the other account as creditor, got %+v", second.Details.Parties)
+	}
+	if st.Summary.Entries != 2 || st.Summary.Net != "466.41" || st.Summary.CdtDbt != "DBIT" {
+		t.Errorf("Unexpected summary %+v", st.Summary)
+	}
+
+	// The reservation on the 26th is written as a pending entry, but is
+	// not counted in the summary
+	doc = camtDocument{}
+	buf, _ = writeTestCamt(t, CamtOptions{
+		From:                time.Date(2019, 10, 23, 0, 0, 0, 0, sbanken.Oslo),
+		To:                  time.Date(2019, 10, 26, 0, 0, 0, 0, sbanken.Oslo),
+		IncludeReservations: true,
+	})
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	st = doc.Statement.Statement
+	if len(st.Entries) != 4 || st.Entries[2].Status != "PDNG" {
+		t.Fatalf("Expected 3 booked entries and a pending one, got %+v", st.Entries)
+	}
+	if st.Summary.Entries != 3 || st.Summary.Sum != "25466.41" || st.Summary.Net != "24533.59" || st.Summary.CdtDbt != "CRDT" {
+		t.Errorf("Expected the summary to count only the booked entries, got %+v", st.Summary)
+	}
+}
+
+func TestTransactionKID(t *testing.T) {
+	cases := map[string]string{
+		"Nettgiro KID: 12345678903": "12345678903",
+		"KID 12345678904":           "",
+		"Betaling kid.1234567-":     "",
+		"REMA 1000":                 "",
+	}
+	for text, expected := range cases {
+		tx := sbanken.Transaction{Text: text}
+		if kid := TransactionKID(&tx); kid != expected {
+			t.Errorf("Expected KID %q in %q, got %q", expected, text, kid)
+		}
+	}
+}
+
+func TestCamt053IdenticalPurchases(t *testing.T) {
+	coffee := sbanken.Transaction{Amount: -4500, AccountingDate: date("2019-10-24T00:00:00"), Text: "KAFFEBRENNERIET", TransactionTypeText: "VARER"}
+	var buf bytes.Buffer
+	opts := CamtOptions{Created: time.Date(2019, 10, 27, 8, 0, 0, 0, sbanken.Oslo)}
+	if err := WriteCamt053(&buf, testAccount, []sbanken.Transaction{coffee, coffee}, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	root, err := parseXML(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	for _, p := range validateCamt(root) {
+		t.Error(p)
+	}
+	var doc camtDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	entries := doc.Statement.Statement.Entries
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Reference != "" || e.ServicerRef != "" || e.Details.Refs != nil {
+			t.Errorf("Expected no references without a transaction ID, got %+v", e)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// Locale decides how values are formatted in CSV files
+type Locale struct {
+	// Separator is the field separator
+	Separator rune
+	// Decimal is the decimal separator used for amounts and rates
+	Decimal string
+	// DateFormat is the time layout used for dates
+	DateFormat string
+	// BOM starts the file with a UTF-8 byte order mark, which some
+	// spreadsheets need to read æ, ø and å correctly
+	BOM bool
+}
+
+// DefaultLocale writes comma separated files with decimal points and
+// ISO 8601 dates
+var DefaultLocale = Locale{Separator: ',', Decimal: ".", DateFormat: "2006-01-02"}
+
+// NorwegianLocale writes files that open correctly in Norwegian Excel,
+// with semicolon separators, decimal commas and dd.mm.yyyy dates
+var NorwegianLocale = Locale{Separator: ';', Decimal: ",", DateFormat: "02.01.2006", BOM: true}
+
+// Column is a column in a CSV file. Field is the name of a Transaction
+// field, like Amount, a card detail, like CardDetails.MerchantName, or
+// one of the derived values GetText and GetTransactionDate. Header
+// defaults to Field
+type Column struct {
+	Header string
+	Field  string
+}
+
+// DefaultColumns are the columns written when CSVOptions has none
+var DefaultColumns = []Column{
+	{Header: "Date", Field: "GetTransactionDate"},
+	{Header: "Text", Field: "GetText"},
+	{Header: "Amount", Field: "Amount"},
+	{Header: "Type", Field: "TransactionTypeText"},
+	{Header: "ID", Field: "TransactionID"},
+}
+
+// CSVOptions adjusts the file written by WriteCSV. The zero value
+// writes DefaultColumns using DefaultLocale
+type CSVOptions struct {
+	Columns []Column
+	// Locale defaults to DefaultLocale
+	Locale *Locale
+	// NoHeader leaves out the header row
+	NoHeader bool
+}
+
+// Columns returns columns for the given field names, using the field
+// names as headers. An error is returned for unknown fields
+func Columns(fields ...string) ([]Column, error) {
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		if _, err := fieldGetter(f); err != nil {
+			return nil, err
+		}
+		columns[i] = Column{Field: f}
+	}
+	return columns, nil
+}
+
+// WriteCSV writes txs as CSV, one row for each transaction
+func WriteCSV(w io.Writer, txs []sbanken.Transaction, opts CSVOptions) error {
+	locale := DefaultLocale
+	if opts.Locale != nil {
+		locale = *opts.Locale
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	getters := make([]func(*sbanken.Transaction) interface{}, len(columns))
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		g, err := fieldGetter(c.Field)
+		if err != nil {
+			return err
+		}
+		getters[i] = g
+		header[i] = c.Header
+		if header[i] == "" {
+			header[i] = c.Field
+		}
+	}
+
+	if locale.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(w)
+	if locale.Separator != 0 {
+		cw.Comma = locale.Separator
+	}
+	if !opts.NoHeader {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(columns))
+	for i := range txs {
+		for j, g := range getters {
+			row[j] = locale.format(g(&txs[i]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// fieldGetter returns a function reading the named field, see Column
+func fieldGetter(field string) (func(*sbanken.Transaction) interface{}, error) {
+	switch field {
+	case "GetText":
+		return func(t *sbanken.Transaction) interface{} { return t.GetText() }, nil
+	case "GetTransactionDate":
+		return func(t *sbanken.Transaction) interface{} { return t.GetTransactionDate() }, nil
+	}
+	path := strings.Split(field, ".")
+	typ := reflect.TypeOf(sbanken.Transaction{})
+	var index []int
+	for _, name := range path {
+		if typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("Unknown transaction field %q", field)
+		}
+		f, ok := typ.FieldByName(name)
+		if !ok || f.PkgPath != "" {
+			return nil, fmt.Errorf("Unknown transaction field %q", field)
+		}
+		index = append(index, f.Index...)
+		typ = f.Type
+	}
+	return func(t *sbanken.Transaction) interface{} {
+		return reflect.ValueOf(t).Elem().FieldByIndex(index).Interface()
+	}, nil
+}
+
+func (l Locale) format(v interface{}) string {
+	switch v := v.(type) {
+	case sbanken.Amount:
+		return l.decimal(v.Decimal())
+	case sbanken.Date:
+		return l.date(v.Time)
+	case time.Time:
+		return l.date(v)
+	case float64:
+		return l.decimal(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+func (l Locale) decimal(s string) string {
+	if l.Decimal == "" || l.Decimal == "." {
+		return s
+	}
+	return strings.Replace(s, ".", l.Decimal, 1)
+}
+
+func (l Locale) date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(sbanken.Oslo).Format(l.DateFormat)
+}
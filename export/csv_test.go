@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testTxs[:2], CSVOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "Date,Text,Amount,Type,ID\n" +
+		"2019-10-23,REMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN,-16.41,VARER,T1\n" +
+		"2019-10-25,Lønn,25000.00,LØNN,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteCSVNorwegian(t *testing.T) {
+	columns, err := Columns("AccountingDate", "Amount", "OtherAccountNumber", "CardDetails.MerchantName", "IsReservation")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	columns[1].Header = "Beløp"
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testTxs[1:], CSVOptions{Columns: columns, Locale: &NorwegianLocale}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "\ufeffAccountingDate;Beløp;OtherAccountNumber;CardDetails.MerchantName;IsReservation\n" +
+		"25.10.2019;25000,00;12345678903;;false\n" +
+		"26.10.2019;-99,00;;;true\n"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestColumnsUnknownField(t *testing.T) {
+	for _, f := range []string{"Nope", "CardDetails.Nope", "Amount.Value"} {
+		if _, err := Columns(f); err == nil {
+			t.Errorf("Expected an error for %s", f)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package export
+
+import (
+	"fmt"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// TransactionIDs returns the identifier each of txs gets in exports:
+// the transaction ID from Sbanken when there is one, otherwise the
+// PurchaseID derived from the transaction. Identical purchases without
+// an ID, like two coffees on the same day, get #2, #3 and so on
+// appended in the order they appear, just as in the sync package, so
+// that importers do not drop them as duplicates
+func TransactionIDs(txs []sbanken.Transaction) []string {
+	ids := make([]string, len(txs))
+	count := map[string]int{}
+	for i := range txs {
+		id := baseID(&txs[i])
+		count[id]++
+		if count[id] > 1 {
+			id = fmt.Sprintf("%s#%d", id, count[id])
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// baseID is the identifier of tx before identical purchases are told
+// apart, see TransactionIDs
+func baseID(tx *sbanken.Transaction) string {
+	if tx.TransactionID != "" {
+		return tx.TransactionID
+	}
+	return tx.PurchaseID()
+}
@@ -0,0 +1,285 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// JournalFormat is a plain text accounting format
+type JournalFormat int
+
+const (
+	// Ledger is the format of ledger-cli
+	Ledger JournalFormat = iota
+	// HLedger is the format of hledger, which is close to Ledger
+	HLedger
+	// Beancount is the format of beancount
+	Beancount
+)
+
+// dedupeKey is the metadata key holding the identifier of each entry.
+// It is read back by ReadJournalKeys to avoid exporting entries twice
+const dedupeKey = "sbanken-id"
+
+var dedupeKeyPattern = regexp.MustCompile(dedupeKey + `:\s*"?([^"\s,]+)`)
+
+// JournalConfig maps Sbanken accounts to journal accounts. It is
+// usually read from a JSON file with LoadJournalConfig, like
+//
+//	{
+//	  "accounts": {"97221912345": "Assets:Sbanken:Brukskonto"},
+//	  "income": "Income:Unknown",
+//	  "expenses": "Expenses:Unknown",
+//	  "transfers": "Equity:Transfers"
+//	}
+type JournalConfig struct {
+	// Accounts maps account numbers to journal accounts. It is used
+	// for the exported account, and tells which other accounts are
+	// your own
+	Accounts map[string]string `json:"accounts"`
+	// Income and Expenses are the counter accounts of transactions to
+	// accounts that are not in Accounts. They default to
+	// Income:Unknown and Expenses:Unknown
+	Income   string `json:"income"`
+	Expenses string `json:"expenses"`
+	// Transfers is the counter account of transfers between accounts
+	// in Accounts, and defaults to Equity:Transfers. Each account posts
+	// its own side of a transfer against it, so exporting both accounts
+	// into one journal does not count the transfer twice, and the
+	// account is back at zero once both sides are in
+	Transfers string `json:"transfers"`
+	// Currency defaults to NOK
+	Currency string `json:"currency"`
+}
+
+// JournalOptions adjusts the entries written by WriteJournal
+type JournalOptions struct {
+	Format JournalFormat
+	Config JournalConfig
+	// Existing holds the keys of entries already in the journal, which
+	// are not written again, see ReadJournalKeys
+	Existing map[string]bool
+	// BalanceDate is the date of the balance assertion, and defaults
+	// to today
+	BalanceDate time.Time
+	// NoBalance leaves out the balance assertion
+	NoBalance bool
+	// IncludeReservations adds reserved transactions as postings
+	// flagged pending with !. The balance assertion does not include
+	// them, so use it with NoBalance
+	IncludeReservations bool
+}
+
+// LoadJournalConfig reads a JournalConfig from a JSON file
+func LoadJournalConfig(path string) (JournalConfig, error) {
+	var config JournalConfig
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("Failed to read journal config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ReadJournalKeys returns the keys of the entries written to a journal
+// by WriteJournal
+func ReadJournalKeys(r io.Reader) (map[string]bool, error) {
+	keys := map[string]bool{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if m := dedupeKeyPattern.FindStringSubmatch(s.Text()); m != nil {
+			keys[m[1]] = true
+		}
+	}
+	return keys, s.Err()
+}
+
+// AppendJournal appends the entries of txs that are not already in the
+// journal at path, creating it if needed. It returns the number of
+// entries written
+func AppendJournal(path string, account sbanken.Account, txs []sbanken.Transaction, opts JournalOptions) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	existing, err := ReadJournalKeys(f)
+	if err != nil {
+		return 0, err
+	}
+	for k := range opts.Existing {
+		existing[k] = true
+	}
+	opts.Existing = existing
+	n, err := writeJournal(f, account, txs, opts)
+	if err != nil {
+		return n, err
+	}
+	return n, f.Close()
+}
+
+// WriteJournal writes txs as journal entries between the journal
+// account of account and a counter account, followed by a balance
+// assertion from Account.Balance. GetText is used as the payee, and
+// the transaction type and MCC are written as metadata together with
+// the key used to avoid duplicates
+func WriteJournal(w io.Writer, account sbanken.Account, txs []sbanken.Transaction, opts JournalOptions) error {
+	_, err := writeJournal(w, account, txs, opts)
+	return err
+}
+
+func writeJournal(w io.Writer, account sbanken.Account, txs []sbanken.Transaction, opts JournalOptions) (int, error) {
+	j := journal{format: opts.Format, config: opts.Config, accounts: map[string]string{}}
+	for number, a := range opts.Config.Accounts {
+		j.accounts[sbanken.NormalizeAccountNumber(number)] = a
+	}
+	own := j.account(account)
+	written := 0
+	bw := bufio.NewWriter(w)
+	ids := TransactionIDs(txs)
+	for i := range txs {
+		tx := &txs[i]
+		key := ids[i]
+		if (tx.IsReservation && !opts.IncludeReservations) || opts.Existing[key] {
+			continue
+		}
+		j.transaction(bw, own, tx, key)
+		written++
+	}
+	if !opts.NoBalance {
+		date := opts.BalanceDate
+		if date.IsZero() {
+			date = time.Now()
+		}
+		date = date.In(sbanken.Oslo)
+		key := "balance-" + sbanken.NormalizeAccountNumber(account.AccountNumber) + "-" + date.Format("20060102")
+		if !opts.Existing[key] {
+			j.balance(bw, own, account.Balance, date, key)
+			written++
+		}
+	}
+	return written, bw.Flush()
+}
+
+type journal struct {
+	format   JournalFormat
+	config   JournalConfig
+	accounts map[string]string
+}
+
+func (j journal) transaction(w *bufio.Writer, own string, tx *sbanken.Transaction, key string) {
+	date := tx.GetTransactionDate().Format("2006-01-02")
+	meta := [][2]string{{dedupeKey, key}}
+	if tx.TransactionTypeText != "" {
+		meta = append(meta, [2]string{"type", tx.TransactionTypeText})
+	}
+	if tx.CardDetails.MerchantCategoryCode != "" {
+		meta = append(meta, [2]string{"mcc", tx.CardDetails.MerchantCategoryCode})
+	}
+	status := "*"
+	if tx.IsReservation {
+		status = "!"
+	}
+	if j.format == Beancount {
+		fmt.Fprintf(w, "%s %s %s \"\"\n", date, status, quote(tx.GetText()))
+		for _, m := range meta {
+			fmt.Fprintf(w, "  %s: %s\n", m[0], quote(m[1]))
+		}
+		fmt.Fprintf(w, "  %s  %s %s\n", own, tx.Amount.Decimal(), j.currency())
+		fmt.Fprintf(w, "  %s\n\n", j.counterAccount(tx))
+		return
+	}
+	fmt.Fprintf(w, "%s %s %s\n", date, status, tx.GetText())
+	for _, m := range meta {
+		fmt.Fprintf(w, "    ; %s: %s\n", m[0], strings.Replace(m[1], ",", " ", -1))
+	}
+	fmt.Fprintf(w, "    %s  %s %s\n", own, tx.Amount.Decimal(), j.currency())
+	fmt.Fprintf(w, "    %s\n\n", j.counterAccount(tx))
+}
+
+func (j journal) balance(w *bufio.Writer, own string, balance sbanken.Amount, date time.Time, key string) {
+	if j.format == Beancount {
+		// Beancount checks the balance at the start of the day
+		fmt.Fprintf(w, "%s balance %s  %s %s\n", date.AddDate(0, 0, 1).Format("2006-01-02"), own, balance.Decimal(), j.currency())
+		fmt.Fprintf(w, "  %s: %s\n\n", dedupeKey, quote(key))
+		return
+	}
+	fmt.Fprintf(w, "%s * Balance\n", date.Format("2006-01-02"))
+	fmt.Fprintf(w, "    ; %s: %s\n", dedupeKey, key)
+	fmt.Fprintf(w, "    %s  0 %s = %s %s\n\n", own, j.currency(), balance.Decimal(), j.currency())
+}
+
+// account returns the journal account of account, which defaults to
+// Assets:Sbanken followed by the account name
+func (j journal) account(account sbanken.Account) string {
+	if a, ok := j.accounts[sbanken.NormalizeAccountNumber(account.AccountNumber)]; ok {
+		return a
+	}
+	return "Assets:Sbanken:" + accountComponent(account.Name)
+}
+
+func (j journal) counterAccount(tx *sbanken.Transaction) string {
+	if tx.OtherAccountNumber != "" {
+		if _, ok := j.accounts[sbanken.NormalizeAccountNumber(tx.OtherAccountNumber)]; ok {
+			if j.config.Transfers != "" {
+				return j.config.Transfers
+			}
+			return "Equity:Transfers"
+		}
+	}
+	if tx.Amount.Sign() > 0 {
+		if j.config.Income != "" {
+			return j.config.Income
+		}
+		return "Income:Unknown"
+	}
+	if j.config.Expenses != "" {
+		return j.config.Expenses
+	}
+	return "Expenses:Unknown"
+}
+
+func (j journal) currency() string {
+	if j.config.Currency != "" {
+		return j.config.Currency
+	}
+	return "NOK"
+}
+
+// accountComponent turns name into an account name component that all
+// the journal formats accept, like Brukskonto or FelleskontoHus
+func accountComponent(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Konto"
+	}
+	return b.String()
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
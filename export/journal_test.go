@@ -0,0 +1,190 @@
+package export
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+var journalConfig = JournalConfig{
+	Accounts: map[string]string{
+		"9722.19.12345": "Assets:Bank:Checking",
+		"12345678903":   "Assets:Bank:Other",
+	},
+}
+
+func journalTxs() []sbanken.Transaction {
+	txs := append([]sbanken.Transaction{}, testTxs...)
+	txs[0].CardDetails.MerchantCategoryCode = "5411"
+	return txs
+}
+
+func TestWriteJournalLedger(t *testing.T) {
+	var buf bytes.Buffer
+	balanceDate := time.Date(2019, 10, 26, 12, 0, 0, 0, sbanken.Oslo)
+	err := WriteJournal(&buf, testAccount, journalTxs(), JournalOptions{Format: HLedger, Config: journalConfig, BalanceDate: balanceDate})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `2019-10-23 * REMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN
+    ; sbanken-id: T1
+    ; type: VARER
+    ; mcc: 5411
+    Assets:Bank:Checking  -16.41 NOK
+    Expenses:Unknown
+
+2019-10-25 * Lønn
+    ; sbanken-id: ` + testTxs[1].PurchaseID() + `
+    ; type: LØNN
+    Assets:Bank:Checking  25000.00 NOK
+    Equity:Transfers
+
+2019-10-26 * Balance
+    ; sbanken-id: balance-97221912345-20191026
+    Assets:Bank:Checking  0 NOK = 1234.56 NOK
+
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteJournalBeancount(t *testing.T) {
+	var buf bytes.Buffer
+	balanceDate := time.Date(2019, 10, 26, 12, 0, 0, 0, sbanken.Oslo)
+	err := WriteJournal(&buf, testAccount, journalTxs()[:1], JournalOptions{Format: Beancount, BalanceDate: balanceDate})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `2019-10-23 * "REMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN" ""
+  sbanken-id: "T1"
+  type: "VARER"
+  mcc: "5411"
+  Assets:Sbanken:Brukskonto  -16.41 NOK
+  Expenses:Unknown
+
+2019-10-27 balance Assets:Sbanken:Brukskonto  1234.56 NOK
+  sbanken-id: "balance-97221912345-20191026"
+
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestAppendJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "main.beancount")
+	opts := JournalOptions{Format: Beancount, NoBalance: true}
+
+	n, err := AppendJournal(path, testAccount, journalTxs()[:1], opts)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 entry, got %d, %v", n, err)
+	}
+	n, err = AppendJournal(path, testAccount, journalTxs(), opts)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected only the new entry to be appended, got %d, %v", n, err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := strings.Count(string(data), "sbanken-id"); c != 2 {
+		t.Errorf("Expected 2 entries in the journal, got %d:\n%s", c, data)
+	}
+}
+
+func TestAppendJournalIdenticalPurchases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "main.journal")
+	opts := JournalOptions{Format: Ledger, NoBalance: true}
+	coffee := sbanken.Transaction{Amount: -4500, AccountingDate: date("2019-10-24T00:00:00"), Text: "KAFFEBRENNERIET", TransactionTypeText: "VARER"}
+	txs := []sbanken.Transaction{coffee, coffee}
+
+	n, err := AppendJournal(path, testAccount, txs, opts)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected both coffees to be written, got %d, %v", n, err)
+	}
+	n, err = AppendJournal(path, testAccount, txs, opts)
+	if err != nil || n != 0 {
+		t.Fatalf("Expected nothing to be appended again, got %d, %v", n, err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := strings.Count(string(data), "KAFFEBRENNERIET"); c != 2 {
+		t.Errorf("Expected 2 entries in the journal, got %d:\n%s", c, data)
+	}
+}
+
+func TestWriteJournalTransferBetweenOwnAccounts(t *testing.T) {
+	checking := sbanken.Account{AccountNumber: "97221912345", Name: "Brukskonto", Balance: -50000}
+	other := sbanken.Account{AccountNumber: "12345678903", Name: "Sparekonto", Balance: 50000}
+	day := date("2019-10-24T00:00:00")
+	out := sbanken.Transaction{TransactionID: "T10", Amount: -50000, AccountingDate: day, Text: "Overføring", OtherAccountNumber: "12345678903"}
+	in := sbanken.Transaction{TransactionID: "T11", Amount: 50000, AccountingDate: day, Text: "Overføring", OtherAccountNumber: "97221912345"}
+	balanceDate := time.Date(2019, 10, 26, 12, 0, 0, 0, sbanken.Oslo)
+	opts := JournalOptions{Format: Ledger, Config: journalConfig, BalanceDate: balanceDate}
+
+	var buf bytes.Buffer
+	if err := WriteJournal(&buf, checking, []sbanken.Transaction{out}, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := WriteJournal(&buf, other, []sbanken.Transaction{in}, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := `2019-10-24 * Overføring
+    ; sbanken-id: T10
+    Assets:Bank:Checking  -500.00 NOK
+    Equity:Transfers
+
+2019-10-26 * Balance
+    ; sbanken-id: balance-97221912345-20191026
+    Assets:Bank:Checking  0 NOK = -500.00 NOK
+
+2019-10-24 * Overføring
+    ; sbanken-id: T11
+    Assets:Bank:Other  500.00 NOK
+    Equity:Transfers
+
+2019-10-26 * Balance
+    ; sbanken-id: balance-12345678903-20191026
+    Assets:Bank:Other  0 NOK = 500.00 NOK
+
+`
+	if buf.String() != expected {
+		t.Errorf("Expected each account to post only its own side\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestLoadJournalConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "journal*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"accounts": {"97221912345": "Assets:Bank"}, "expenses": "Expenses:Misc"}`)
+	f.Close()
+	config, err := LoadJournalConfig(f.Name())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if config.Accounts["97221912345"] != "Assets:Bank" || config.Expenses != "Expenses:Misc" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+}
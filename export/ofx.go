@@ -0,0 +1,274 @@
+// Package export writes transactions from Sbanken in formats that
+// accounting tools and spreadsheets can import.
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxDateFormat is the date only variant of the OFX date format
+const ofxDateFormat = "20060102"
+
+// ofxNameLength is the longest NAME allowed by OFX
+const ofxNameLength = 32
+
+// OFXOptions adjusts the OFX document written by WriteOFX
+type OFXOptions struct {
+	// Start and End are the period covered by the statement. They
+	// default to the first and last transaction date
+	Start time.Time
+	End   time.Time
+	// IncludeReservations adds reserved transactions as ordinary
+	// STMTTRN entries, since OFX has no pending state. The FITID
+	// usually changes when they are booked, so importers may end up
+	// with both the reservation and the booked transaction
+	IncludeReservations bool
+}
+
+// OFXStatement is a statement as read by ReadOFX
+type OFXStatement struct {
+	BankID        string
+	AccountID     string
+	AccountType   string
+	Currency      string
+	Start         time.Time
+	End           time.Time
+	LedgerBalance sbanken.Amount
+	BalanceDate   time.Time
+	Transactions  []OFXTransaction
+}
+
+// OFXTransaction is a single STMTTRN in an OFX statement
+type OFXTransaction struct {
+	Type   string
+	Posted time.Time
+	User   time.Time
+	Amount sbanken.Amount
+	FITID  string
+	Name   string
+	Memo   string
+}
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			Server   string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Response struct {
+			TrnUID    string          `xml:"TRNUID"`
+			Status    ofxStatus       `xml:"STATUS"`
+			Statement ofxStatementXML `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxStatementXML struct {
+	Currency string `xml:"CURDEF"`
+	Account  struct {
+		BankID      string `xml:"BANKID"`
+		AccountID   string `xml:"ACCTID"`
+		AccountType string `xml:"ACCTTYPE"`
+	} `xml:"BANKACCTFROM"`
+	TransactionList struct {
+		Start        string              `xml:"DTSTART"`
+		End          string              `xml:"DTEND"`
+		Transactions []ofxTransactionXML `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	LedgerBalance ofxBalance `xml:"LEDGERBAL"`
+	Available     ofxBalance `xml:"AVAILBAL"`
+}
+
+type ofxTransactionXML struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	User   string `xml:"DTUSER,omitempty"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME,omitempty"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	AsOf   string `xml:"DTASOF"`
+}
+
+// WriteOFX writes the transactions on account as an OFX 2.1.1 bank
+// statement (STMTRS), with the ledger and available balance of the
+// account. Each transaction gets a FITID that stays the same if the
+// statement is exported again, see TransactionIDs.
+func WriteOFX(w io.Writer, account sbanken.Account, txs []sbanken.Transaction, opts OFXOptions) error {
+	now := time.Now().In(sbanken.Oslo)
+	var doc ofxDocument
+	doc.SignOn.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.Response.Server = now.Format("20060102150405")
+	doc.SignOn.Response.Language = "NOR"
+	doc.Bank.Response.TrnUID = "1"
+	doc.Bank.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+
+	st := &doc.Bank.Response.Statement
+	st.Currency = "NOK"
+	st.Account.BankID = bankID(account.AccountNumber)
+	st.Account.AccountID = account.AccountNumber
+	st.Account.AccountType = ofxAccountType(account.AccountType)
+
+	start, end := opts.Start, opts.End
+	ids := TransactionIDs(txs)
+	for i := range txs {
+		tx := &txs[i]
+		if tx.IsReservation && !opts.IncludeReservations {
+			continue
+		}
+		posted := tx.GetAccountingDate()
+		if posted.IsZero() {
+			posted = tx.GetTransactionDate()
+		}
+		if opts.Start.IsZero() && (start.IsZero() || posted.Before(start)) {
+			start = posted
+		}
+		if opts.End.IsZero() && posted.After(end) {
+			end = posted
+		}
+		st.TransactionList.Transactions = append(st.TransactionList.Transactions, ofxTransactionXML{
+			Type:   ofxTransactionType(tx),
+			Posted: posted.Format(ofxDateFormat),
+			User:   tx.GetTransactionDate().Format(ofxDateFormat),
+			Amount: tx.Amount.Decimal(),
+			FITID:  ids[i],
+			Name:   truncate(tx.GetText(), ofxNameLength),
+			Memo:   tx.TransactionTypeText,
+		})
+	}
+	if end.IsZero() {
+		end = now
+	}
+	if start.IsZero() {
+		start = end
+	}
+	st.TransactionList.Start = start.Format(ofxDateFormat)
+	st.TransactionList.End = end.Format(ofxDateFormat)
+	st.LedgerBalance = ofxBalance{Amount: account.Balance.Decimal(), AsOf: now.Format(ofxDateFormat)}
+	st.Available = ofxBalance{Amount: account.Available.Decimal(), AsOf: now.Format(ofxDateFormat)}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("Failed to write OFX: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadOFX reads a bank statement written by WriteOFX, or by another
+// tool using OFX 2.x
+func ReadOFX(r io.Reader) (OFXStatement, error) {
+	var doc ofxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return OFXStatement{}, fmt.Errorf("Failed to read OFX: %w", err)
+	}
+	st := doc.Bank.Response.Statement
+	result := OFXStatement{
+		BankID:      st.Account.BankID,
+		AccountID:   st.Account.AccountID,
+		AccountType: st.Account.AccountType,
+		Currency:    st.Currency,
+	}
+	var err error
+	if result.Start, err = parseOFXDate(st.TransactionList.Start); err != nil {
+		return result, err
+	}
+	if result.End, err = parseOFXDate(st.TransactionList.End); err != nil {
+		return result, err
+	}
+	if result.LedgerBalance, err = sbanken.ParseAmount(st.LedgerBalance.Amount); err != nil {
+		return result, err
+	}
+	if result.BalanceDate, err = parseOFXDate(st.LedgerBalance.AsOf); err != nil {
+		return result, err
+	}
+	for _, t := range st.TransactionList.Transactions {
+		tx := OFXTransaction{Type: t.Type, FITID: t.FITID, Name: t.Name, Memo: t.Memo}
+		if tx.Posted, err = parseOFXDate(t.Posted); err != nil {
+			return result, err
+		}
+		if tx.User, err = parseOFXDate(t.User); err != nil {
+			return result, err
+		}
+		if tx.Amount, err = sbanken.ParseAmount(t.Amount); err != nil {
+			return result, err
+		}
+		result.Transactions = append(result.Transactions, tx)
+	}
+	return result, nil
+}
+
+// parseOFXDate reads the date part of an OFX date, like 20210322 or
+// 20210322120000.000[+1:CET]
+func parseOFXDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if len(s) < len(ofxDateFormat) {
+		return time.Time{}, fmt.Errorf("Invalid OFX date %q", s)
+	}
+	return time.ParseInLocation(ofxDateFormat, s[:len(ofxDateFormat)], sbanken.Oslo)
+}
+
+func ofxTransactionType(tx *sbanken.Transaction) string {
+	if tx.Amount.Sign() >= 0 {
+		return "CREDIT"
+	}
+	return "DEBIT"
+}
+
+func ofxAccountType(accountType string) string {
+	t := strings.ToLower(accountType)
+	switch {
+	case strings.Contains(t, "spare") || strings.Contains(t, "saving"):
+		return "SAVINGS"
+	case strings.Contains(t, "kreditt") || strings.Contains(t, "credit"):
+		return "CREDITLINE"
+	}
+	return "CHECKING"
+}
+
+// bankID returns the bank registration number, the first four digits
+// of a Norwegian account number
+func bankID(accountNumber string) string {
+	n := sbanken.NormalizeAccountNumber(accountNumber)
+	if len(n) < 4 {
+		return n
+	}
+	return n[:4]
+}
+
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
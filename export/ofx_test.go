@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+func date(s string) sbanken.Date {
+	d, err := sbanken.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+var (
+	testAccount = sbanken.Account{AccountID: "A", AccountNumber: "97221912345", Name: "Brukskonto", AccountType: "Standard account", Balance: 123456, Available: 100000}
+	testTxs     = []sbanken.Transaction{
+		{TransactionID: "T1", Amount: -1641, AccountingDate: date("2019-10-23T00:00:00"), Text: "23.10 REMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN",
+			TransactionTypeText: "VARER", TransactionTypeCode: 710},
+		{Amount: 2500000, AccountingDate: date("2019-10-25T00:00:00"), Text: "Lønn", TransactionTypeText: "LØNN", OtherAccountNumber: "12345678903"},
+		{IsReservation: true, Amount: -9900, AccountingDate: date("2019-10-26T00:00:00"), Text: "KIWI"},
+	}
+)
+
+func TestOFXRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, testAccount, testTxs, OFXOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !strings.Contains(buf.String(), `OFXHEADER="200"`) {
+		t.Errorf("Expected an OFX 2 header, got %s", buf.String())
+	}
+	st, err := ReadOFX(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if st.BankID != "9722" || st.AccountID != "97221912345" || st.LedgerBalance != 123456 {
+		t.Errorf("Unexpected statement %+v", st)
+	}
+	if len(st.Transactions) != 2 {
+		t.Fatalf("Expected reservations to be left out, got %d transactions", len(st.Transactions))
+	}
+	first := st.Transactions[0]
+	if first.FITID != "T1" || first.Amount != -1641 || first.Type != "DEBIT" || first.Memo != "VARER" {
+		t.Errorf("Unexpected transaction %+v", first)
+	}
+	if first.Name != "REMA SPECTRUM FOLKE BERNAD FYLLI" {
+		t.Errorf("Expected the name to be cut at 32 characters, got %q", first.Name)
+	}
+	if got := first.User.Format("2006-01-02"); got != "2019-10-23" {
+		t.Errorf("Expected user date 2019-10-23, got %s", got)
+	}
+	second := st.Transactions[1]
+	if second.FITID != testTxs[1].PurchaseID() || second.Type != "CREDIT" {
+		t.Errorf("Unexpected transaction %+v", second)
+	}
+	if st.Start.Format("2006-01-02") != "2019-10-23" || st.End.Format("2006-01-02") != "2019-10-25" {
+		t.Errorf("Unexpected period %s - %s", st.Start, st.End)
+	}
+}
+
+func TestOFXIdenticalPurchases(t *testing.T) {
+	coffee := sbanken.Transaction{Amount: -4500, AccountingDate: date("2019-10-24T00:00:00"), Text: "KAFFEBRENNERIET", TransactionTypeText: "VARER"}
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, testAccount, []sbanken.Transaction{coffee, coffee}, OFXOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	st, err := ReadOFX(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(st.Transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(st.Transactions))
+	}
+	first, second := st.Transactions[0].FITID, st.Transactions[1].FITID
+	if first != coffee.PurchaseID() || second != coffee.PurchaseID()+"#2" {
+		t.Errorf("Expected the second coffee to get its own FITID, got %s and %s", first, second)
+	}
+}
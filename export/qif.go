@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// QIFOptions adjusts the file written by WriteQIF
+type QIFOptions struct {
+	// DateFormat is the time layout used for dates, and defaults to
+	// the common QIF layout 01/02/2006
+	DateFormat string
+	// IncludeReservations adds reserved transactions as uncleared
+	// entries, without the C line of booked ones
+	IncludeReservations bool
+}
+
+// WriteQIF writes txs as a QIF file of the Bank type. Booked
+// transactions are marked as cleared (C*), leaving reconciliation to
+// the application importing the file
+func WriteQIF(w io.Writer, txs []sbanken.Transaction, opts QIFOptions) error {
+	layout := opts.DateFormat
+	if layout == "" {
+		layout = "01/02/2006"
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("!Type:Bank\n")
+	for i := range txs {
+		tx := &txs[i]
+		if tx.IsReservation && !opts.IncludeReservations {
+			continue
+		}
+		bw.WriteString("D" + tx.GetTransactionDate().Format(layout) + "\n")
+		bw.WriteString("T" + tx.Amount.Decimal() + "\n")
+		if !tx.IsReservation {
+			bw.WriteString("C*\n")
+		}
+		bw.WriteString("P" + qifLine(tx.GetText()) + "\n")
+		if tx.TransactionTypeText != "" {
+			bw.WriteString("M" + qifLine(tx.TransactionTypeText) + "\n")
+		}
+		if tx.TransactionID != "" {
+			bw.WriteString("N" + tx.TransactionID + "\n")
+		}
+		bw.WriteString("^\n")
+	}
+	return bw.Flush()
+}
+
+// qifLine keeps a value on a single line, as QIF is line based
+func qifLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
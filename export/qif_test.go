@@ -0,0 +1,19 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteQIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteQIF(&buf, testTxs, QIFOptions{}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	expected := "!Type:Bank\n" +
+		"D10/23/2019\nT-16.41\nC*\nPREMA SPECTRUM FOLKE BERNAD FYLLINGSDALEN\nMVARER\nNT1\n^\n" +
+		"D10/25/2019\nT25000.00\nC*\nPLønn\nMLØNN\n^\n"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/elzapp/go-sbanken
 
-go 1.13
+go 1.15
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
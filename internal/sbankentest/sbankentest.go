@@ -0,0 +1,60 @@
+// Package sbankentest holds fakes shared by the tests of the packages
+// that read from Sbanken.
+package sbankentest
+
+import (
+	"context"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// Source returns fixed data in place of an *sbanken.APIConnection, and
+// records the period last asked for
+type Source struct {
+	Accounts     []sbanken.Account
+	Transactions []sbanken.Transaction
+	Cards        []sbanken.Card
+	Payments     []sbanken.Payment
+	EFakturas    []sbanken.EFaktura
+
+	From time.Time
+	To   time.Time
+}
+
+// GetAccountsContext returns Accounts
+func (s *Source) GetAccountsContext(ctx context.Context) ([]sbanken.Account, error) {
+	return s.Accounts, nil
+}
+
+// GetTransactionsBetweenContext returns Transactions, whatever the
+// period, and records from and to
+func (s *Source) GetTransactionsBetweenContext(ctx context.Context, accountID string, from, to time.Time) ([]sbanken.Transaction, error) {
+	s.From, s.To = from, to
+	return s.Transactions, nil
+}
+
+// GetCardsContext returns Cards
+func (s *Source) GetCardsContext(ctx context.Context) ([]sbanken.Card, error) {
+	return s.Cards, nil
+}
+
+// GetPaymentsContext returns Payments, whatever the account
+func (s *Source) GetPaymentsContext(ctx context.Context, accountID string) ([]sbanken.Payment, error) {
+	return s.Payments, nil
+}
+
+// GetAllEFakturasContext returns EFakturas
+func (s *Source) GetAllEFakturasContext(ctx context.Context) ([]sbanken.EFaktura, error) {
+	return s.EFakturas, nil
+}
+
+// Date parses a date like 2021-03-22T00:00:00, and panics if it is
+// invalid
+func Date(s string) sbanken.Date {
+	d, err := sbanken.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
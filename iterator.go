@@ -0,0 +1,129 @@
+package sbanken
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TransactionOptions limits the transactions returned by
+// APIConnection.Transactions. The dates are taken as the calendar day
+// they fall on in Oslo, whatever their location
+type TransactionOptions struct {
+	// StartDate is the first accounting date to include. Defaults to
+	// 366 days before EndDate
+	StartDate time.Time
+	// EndDate is the last accounting date to include. Defaults to today
+	EndDate time.Time
+	// PageSize is the number of transactions requested at a time.
+	// Defaults to, and can not be larger than, 1000
+	PageSize int
+}
+
+// TransactionIterator walks through the transactions on an account,
+// requesting a new page from Sbanken only when the previous one has
+// been consumed. Periods longer than 366 days are split into several
+// periods, starting with the oldest.
+//
+//	it := conn.Transactions(ctx, accountID, sbanken.TransactionOptions{StartDate: from})
+//	for it.Next() {
+//		tx := it.Transaction()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type TransactionIterator struct {
+	conn      *APIConnection
+	ctx       context.Context
+	accountID string
+	to        time.Time
+	pageSize  int
+
+	start      time.Time
+	index      int
+	periodDone bool
+	page       []Transaction
+	pos        int
+	current    Transaction
+	err        error
+}
+
+// Transactions returns an iterator over the transactions on a given
+// account. No requests are made until Next is called.
+func (conn *APIConnection) Transactions(ctx context.Context, accountID string, opts TransactionOptions) *TransactionIterator {
+	it := &TransactionIterator{
+		conn:      conn,
+		ctx:       ctx,
+		accountID: accountID,
+		to:        opts.EndDate,
+		start:     opts.StartDate,
+		pageSize:  opts.PageSize,
+	}
+	if it.to.IsZero() {
+		it.to = time.Now()
+	}
+	it.to = osloDay(it.to)
+	if it.start.IsZero() {
+		it.start = it.to.AddDate(0, 0, -(maxTransactionPeriod - 1))
+	}
+	it.start = osloDay(it.start)
+	if it.pageSize <= 0 || it.pageSize > transactionPageSize {
+		it.pageSize = transactionPageSize
+	}
+	if it.to.Before(it.start) {
+		it.err = fmt.Errorf("End date %s is before start date %s: %w", it.to.Format(queryDateFormat), it.start.Format(queryDateFormat), ErrValidation)
+	}
+	return it
+}
+
+// Next advances to the next transaction, fetching another page when
+// needed. It returns false when there are no more transactions or
+// an error occurred, see Err.
+func (it *TransactionIterator) Next() bool {
+	for it.err == nil {
+		if it.pos < len(it.page) {
+			it.current = it.page[it.pos]
+			it.pos++
+			return true
+		}
+		if it.periodDone {
+			it.start = it.periodEnd().AddDate(0, 0, 1)
+			it.index = 0
+			it.periodDone = false
+		}
+		if it.start.After(it.to) {
+			return false
+		}
+		page, err := it.conn.getTransactionPage(it.ctx, it.accountID, it.start, it.periodEnd(), it.index, it.pageSize)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.pos = page.Transactions, 0
+		it.index += len(page.Transactions)
+		if len(page.Transactions) == 0 || int64(it.index) >= page.AvailableItems {
+			it.periodDone = true
+		}
+	}
+	return false
+}
+
+// Transaction returns the transaction Next advanced to
+func (it *TransactionIterator) Transaction() Transaction {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
+
+// periodEnd returns the last day of the period currently being read
+func (it *TransactionIterator) periodEnd() time.Time {
+	end := it.start.AddDate(0, 0, maxTransactionPeriod-1)
+	if end.After(it.to) {
+		return it.to
+	}
+	return end
+}
@@ -1,8 +1,11 @@
 package sbanken
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -15,25 +18,34 @@ import (
 )
 
 const dateFormat = "2006-01-02T15:04:05" //2019-03-06T00:00:00 (used to be 2006-01-02T15:04:05-07:00)
+const queryDateFormat = "2006-01-02"
 const identityserver = "https://auth.sbanken.no/identityserver/connect/token"
-const apiAccounts = "https://publicapi.sbanken.no/apibeta/api/v1/Accounts"
-const apiTransactions = "https://publicapi.sbanken.no/apibeta/api/v1/Transactions/%s"
+const apiBase = "https://publicapi.sbanken.no/apibeta/api/v1"
+const apiAccounts = "/Accounts"
+const apiTransactions = "/Transactions/%s"
+const defaultUserAgent = "github.com/elzapp/go-sbanken"
+const defaultTimeout = time.Second * 10
 
 type accounts struct {
 	Accounts []Account `json:"items"`
 	errorInformation
 }
 
+type accountItemResponse struct {
+	Item Account `json:"item"`
+	errorInformation
+}
+
 // Account information
 type Account struct {
-	AccountID       string  `json:"accountId"`
-	AccountNumber   string  `json:"accountNumber"`
-	OwnerCustomerID string  `json:"ownerCustomerId"`
-	Name            string  `json:"name"`
-	AccountType     string  `json:"accountType"`
-	Available       float64 `json:"available"`
-	Balance         float64 `json:"balance"`
-	CreditLimit     float64 `json:"creditLimit"`
+	AccountID       string `json:"accountId"`
+	AccountNumber   string `json:"accountNumber"`
+	OwnerCustomerID string `json:"ownerCustomerId"`
+	Name            string `json:"name"`
+	AccountType     string `json:"accountType"`
+	Available       Amount `json:"available"`
+	Balance         Amount `json:"balance"`
+	CreditLimit     Amount `json:"creditLimit"`
 }
 
 type errorInformation struct {
@@ -50,21 +62,24 @@ type Credentials struct {
 }
 
 type tokenResponse struct {
-	Token string `json:"access_token"`
+	Token            string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
 }
 
 // Transaction information
 type Transaction struct {
 	TransactionID        string      `json:"transactionId"`
-	AccountingDate       string      `json:"accountingDate"`
-	InterestDate         string      `json:"interestDate"`
+	AccountingDate       Date        `json:"accountingDate"`
+	InterestDate         Date        `json:"interestDate"`
 	OtherAccountNumber   string      `json:"otherAccountNumber"`
 	TransactionType      string      `json:"transactionType"`
 	TransactionTypeCode  int64       `json:"transactionTypeCode"`
 	TransactionTypeText  string      `json:"transactionTypeText"`
 	IsReservation        bool        `json:"isReservation"`
 	CardDetailsSpecified bool        `json:"cardDetailsSpecified"`
-	Amount               float64     `json:"amount"`
+	Amount               Amount      `json:"amount"`
 	Text                 string      `json:"text"`
 	Source               string      `json:"source"`
 	CardDetails          cardDetails `json:"cardDetails"`
@@ -72,27 +87,25 @@ type Transaction struct {
 
 type cardDetails struct {
 	CardNumber                  string  `json:"cardNumber"`
-	CurrencyAmount              float64 `json:"currencyAmount"`
+	CurrencyAmount              Amount  `json:"currencyAmount"`
 	CurrencyRate                float64 `json:"currencyRate"`
 	MerchantCategoryCode        string  `json:"merchantCategoryCode"`
 	MerchantCategoryDescription string  `json:"merchantCategoryDescription"`
 	MerchantCity                string  `json:"merchantCity"`
 	MerchantName                string  `json:"merchantName"`
 	OriginalCurrencyCode        string  `json:"originalCurrencyCode"`
-	PurchaseDate                string  `json:"purchaseDate"`
+	PurchaseDate                Date    `json:"purchaseDate"`
 	TransactionID               string  `json:"transactionId"`
 }
 
 // GetInterestDate returns the interest date as a Time struct
 func (t *Transaction) GetInterestDate() time.Time {
-	r, _ := time.Parse(dateFormat, t.InterestDate)
-	return r
+	return t.InterestDate.Time
 }
 
-// GetAccountingDate returns the interest date as a Time struct
+// GetAccountingDate returns the accounting date as a Time struct
 func (t *Transaction) GetAccountingDate() time.Time {
-	r, _ := time.Parse(dateFormat, t.AccountingDate)
-	return r
+	return t.AccountingDate.Time
 }
 
 // GetTransactionDate makes a best effort at getting the actual
@@ -100,9 +113,8 @@ func (t *Transaction) GetAccountingDate() time.Time {
 // and the archived Transaction
 func (t *Transaction) GetTransactionDate() time.Time {
 
-	if t.CardDetails.PurchaseDate != "" {
-		r, _ := time.Parse(dateFormat, t.CardDetails.PurchaseDate)
-		return r
+	if !t.CardDetails.PurchaseDate.IsZero() {
+		return t.CardDetails.PurchaseDate.Time
 	}
 	if len(t.Text) < 5 {
 		return t.GetAccountingDate()
@@ -112,7 +124,7 @@ func (t *Transaction) GetTransactionDate() time.Time {
 		d := strings.SplitN(datepart, ".", 2)
 		day, _ := strconv.Atoi(d[0])
 		month, _ := strconv.Atoi(d[1])
-		dd := time.Date(t.GetAccountingDate().Year(), time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		dd := time.Date(t.GetAccountingDate().Year(), time.Month(month), day, 0, 0, 0, 0, Oslo)
 		if t.GetAccountingDate().Sub(dd) < time.Duration(0) {
 			dd = dd.AddDate(-1, 0, 0)
 		}
@@ -126,7 +138,7 @@ func (t *Transaction) GetTransactionDate() time.Time {
 		d := strings.SplitN(datepart, ".", 2)
 		day, _ := strconv.Atoi(d[0])
 		month, _ := strconv.Atoi(d[1])
-		dd := time.Date(t.GetAccountingDate().Year(), time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		dd := time.Date(t.GetAccountingDate().Year(), time.Month(month), day, 0, 0, 0, 0, Oslo)
 		if t.GetAccountingDate().Sub(dd) < time.Duration(0) {
 			dd = dd.AddDate(-1, 0, 0)
 		}
@@ -158,67 +170,181 @@ func (t *Transaction) GetText() string {
 }
 
 type transactions struct {
-	Transactions []Transaction `json:"items"`
+	AvailableItems int64         `json:"availableItems"`
+	Transactions   []Transaction `json:"items"`
 	errorInformation
 }
 
+// maxTransactionPeriod is the longest period, in days, that can be
+// requested from the Transactions endpoint at once
+const maxTransactionPeriod = 366
+
+// transactionPageSize is the largest number of transactions
+// Sbanken returns in a single response
+const transactionPageSize = 1000
+
 // APIConnection is the Api client
 type APIConnection struct {
 	cred           Credentials
-	token          string
+	auth           *tokenCache
+	client         *http.Client
+	baseURL        string
+	authURL        string
+	userAgent      string
 	makeAPIRequest func(r apirequest) ([]byte, error)
 }
 
+// tokenRefreshMargin is how long before the reported expiry a
+// token is considered stale and will be replaced
+const tokenRefreshMargin = time.Minute
+
+// tokenCache holds the access token shared by all copies of an
+// APIConnection. The lock also ensures that only one goroutine talks
+// to the identityserver at a time. It is a channel rather than a mutex
+// so that goroutines waiting for a token can give up when their
+// context is done.
+type tokenCache struct {
+	lock    chan struct{}
+	token   string
+	expires time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{lock: make(chan struct{}, 1)}
+}
+
+// acquire takes the lock, or returns the error of ctx if it is done
+// first
+func (c *tokenCache) acquire(ctx context.Context) error {
+	select {
+	case c.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *tokenCache) release() {
+	<-c.lock
+}
+
+// valid reports whether the cached token can still be used.
+// The caller must hold the lock.
+func (c *tokenCache) valid(now time.Time) bool {
+	if c.token == "" {
+		return false
+	}
+	return c.expires.IsZero() || now.Before(c.expires.Add(-tokenRefreshMargin))
+}
+
+// invalidate drops the cached token, unless it has already been
+// replaced by another goroutine
+func (c *tokenCache) invalidate(token string) {
+	c.acquire(context.Background())
+	defer c.release()
+	if c.token == token {
+		c.token = ""
+		c.expires = time.Time{}
+	}
+}
+
 // HasToken returns true if this session has been authenticated
+// and the token has not expired
 func (conn *APIConnection) HasToken() bool {
-	if conn.token == "" {
+	if conn.auth == nil {
 		return false
 	}
-	return true
+	conn.auth.acquire(context.Background())
+	defer conn.auth.release()
+	return conn.auth.valid(time.Now())
 }
 
-func (conn *APIConnection) getToken() (string, error) {
-	if conn.token == "" {
-		log.Debug("Getting token")
-		postdata := url.Values{}
-		postdata.Add("grant_type", "client_credentials")
-		req, err := http.NewRequest("POST", identityserver, strings.NewReader(postdata.Encode()))
-		if err != nil {
-			return "", fmt.Errorf("Failed to create request %w", err)
-		}
-		req.Header.Add("Content-type", "application/x-www-form-urlencoded; charset=utf-8")
-		req.Header.Add("User-Agent", "github.com/elzapp/go-sbanken")
-		req.SetBasicAuth(conn.cred.Apikey, url.QueryEscape(conn.cred.Secret))
-		cli := &http.Client{}
-		resp, err := cli.Do(req)
-		if err != nil {
-			return "", fmt.Errorf("Failed to get token: %w", err)
+func (conn *APIConnection) getToken(ctx context.Context) (string, error) {
+	if err := conn.auth.acquire(ctx); err != nil {
+		return "", fmt.Errorf("Failed to get token: %w", err)
+	}
+	defer conn.auth.release()
+	if conn.auth.valid(time.Now()) {
+		return conn.auth.token, nil
+	}
+	log.Debug("Getting token")
+	postdata := url.Values{}
+	postdata.Add("grant_type", "client_credentials")
+	req, err := http.NewRequestWithContext(ctx, "POST", conn.authURL, strings.NewReader(postdata.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("Failed to create request %w", err)
+	}
+	req.Header.Add("Content-type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Add("User-Agent", conn.userAgent)
+	req.SetBasicAuth(conn.cred.Apikey, url.QueryEscape(conn.cred.Secret))
+	requested := time.Now()
+	resp, err := conn.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read token response: %w", err)
+	}
+	var t tokenResponse
+	decodeErr := json.Unmarshal(body, &t)
+	if resp.StatusCode > 399 {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			ErrorType:  t.Error,
+			Message:    t.ErrorDescription,
+			target:     conn.authURL,
 		}
-		if resp.StatusCode == 400 {
-			return "", fmt.Errorf("Got \"%s\" while requesting token, check that your secret is valid", resp.Status)
-		} else if resp.StatusCode > 399 {
-			return "", fmt.Errorf("Got \"%s\" while requesting token (%+v)", resp.Status, resp)
+		if decodeErr != nil {
+			// Not an OAuth error, like an HTML page from a proxy
+			apiErr.Message = strings.TrimSpace(string(body))
 		}
-		defer resp.Body.Close()
-		body, _ := ioutil.ReadAll(resp.Body)
-		var t tokenResponse
-		json.Unmarshal(body, &t)
-		if t.Token == "" {
-			log.Errorf("Received empty token from identityserver (%s)", body)
+		if resp.StatusCode == 400 || resp.StatusCode == 401 {
+			hint := "check that your secret is valid"
+			if apiErr.Message != "" {
+				hint = apiErr.Message + " (" + hint + ")"
+			}
+			apiErr.Message = hint
+			apiErr.kind = ErrUnauthorized
 		}
-		conn.token = t.Token
+		return "", apiErr
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("Failed to decode token response: %w", decodeErr)
+	}
+	if t.Token == "" {
+		return "", fmt.Errorf("Received an empty token from the identityserver (%s)", body)
 	}
-	return conn.token, nil
+	conn.auth.token = t.Token
+	conn.auth.expires = time.Time{}
+	if t.ExpiresIn > 0 {
+		conn.auth.expires = requested.Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return conn.auth.token, nil
+}
+
+// endpoint returns the full URL for an API path, formatted with args
+func (conn *APIConnection) endpoint(path string, args ...interface{}) string {
+	if len(args) > 0 {
+		path = fmt.Sprintf(path, args...)
+	}
+	return strings.TrimSuffix(conn.baseURL, "/") + path
 }
 
 type apirequest struct {
+	ctx     context.Context
+	method  string
 	target  string
 	params  map[string]string
 	headers map[string]string
+	body    interface{}
 }
 
-func newAPIRequest() apirequest {
+func newAPIRequest(ctx context.Context) apirequest {
 	var r apirequest
+	r.ctx = ctx
+	r.method = http.MethodGet
 	r.params = map[string]string{}
 	r.headers = map[string]string{}
 	return r
@@ -227,47 +353,119 @@ func newAPIRequest() apirequest {
 // GetAccounts returns a list of all your bank accounts
 // See the Account struct for details
 func (conn *APIConnection) GetAccounts() ([]Account, error) {
-	r := newAPIRequest()
-	r.target = apiAccounts
+	return conn.GetAccountsContext(context.Background())
+}
+
+// GetAccountsContext is like GetAccounts, but the request is
+// bound to ctx
+func (conn *APIConnection) GetAccountsContext(ctx context.Context) ([]Account, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(apiAccounts)
 	var a accounts
-	resp, err := conn.makeAPIRequest(r)
-	if err != nil {
+	if err := conn.requestJSON(r, &a); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &a)
 	return a.Accounts, nil
 }
 
+// GetAccount returns a single bank account. The returned error
+// matches ErrNotFound if there is no such account
+func (conn *APIConnection) GetAccount(accountID string) (Account, error) {
+	return conn.GetAccountContext(context.Background(), accountID)
+}
+
+// GetAccountContext is like GetAccount, but the request is bound to ctx
+func (conn *APIConnection) GetAccountContext(ctx context.Context, accountID string) (Account, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(apiAccounts + "/" + accountID)
+	var a accountItemResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return Account{}, err
+	}
+	if a.Item.AccountID == "" {
+		return Account{}, fmt.Errorf("Account %s was not found: %w", accountID, ErrNotFound)
+	}
+	return a.Item, nil
+}
+
 // GetTransactions returns the latest transactions on a given account
 // using the default limits set by Sbanken
 func (conn *APIConnection) GetTransactions(accountid string) ([]Transaction, error) {
-	r := newAPIRequest()
-	r.target = fmt.Sprintf(apiTransactions, accountid)
+	return conn.GetTransactionsContext(context.Background(), accountid)
+}
+
+// GetTransactionsContext is like GetTransactions, but the request is
+// bound to ctx
+func (conn *APIConnection) GetTransactionsContext(ctx context.Context, accountid string) ([]Transaction, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(apiTransactions, accountid)
 	var t transactions
-	resp, err := conn.makeAPIRequest(r)
-	if err != nil {
+	if err := conn.requestJSON(r, &t); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &t)
 	return t.Transactions, nil
 }
 
-// GetTransactionsSince returns the latest transactions on a given account
-// for a given period. The period must be less than, or equal to 366 days.
-// At this point this will only return the last 1000 transactions in the
-// period
+// GetTransactionsSince returns the transactions on a given account
+// from startDate (formatted as 2006-01-02) until today.
+//
+// Deprecated: errors are only logged, use GetTransactionsBetween instead
 func (conn *APIConnection) GetTransactionsSince(accountid string, startDate string) []Transaction {
-	r := newAPIRequest()
-	r.target = fmt.Sprintf(apiTransactions, accountid)
-	sd := time.Now()
-	sd = sd.AddDate(-1, 0, 0)
-	r.params["startDate"] = sd.Format("2006-01-02")
-	r.params["length"] = "1000"
+	return conn.GetTransactionsSinceContext(context.Background(), accountid, startDate)
+}
+
+// GetTransactionsSinceContext is like GetTransactionsSince, but the
+// request is bound to ctx
+//
+// Deprecated: errors are only logged, use GetTransactionsBetweenContext instead
+func (conn *APIConnection) GetTransactionsSinceContext(ctx context.Context, accountid string, startDate string) []Transaction {
+	from, err := time.Parse(queryDateFormat, startDate)
+	if err != nil {
+		log.Errorf("Invalid start date %q: %s", startDate, err)
+		return nil
+	}
+	t, err := conn.GetTransactionsBetweenContext(ctx, accountid, from, time.Now())
+	if err != nil {
+		log.Errorf("Failed to get transactions since %s: %s", startDate, err)
+	}
+	return t
+}
+
+// GetTransactionsBetween returns all transactions on a given account
+// with an accounting date from and including from, to and including to.
+// Periods longer than Sbanken's limit of 366 days are split into several
+// requests, and each period is paged through until all available
+// transactions have been fetched. The oldest period is returned first.
+func (conn *APIConnection) GetTransactionsBetween(accountID string, from, to time.Time) ([]Transaction, error) {
+	return conn.GetTransactionsBetweenContext(context.Background(), accountID, from, to)
+}
+
+// GetTransactionsBetweenContext is like GetTransactionsBetween, but the
+// requests are bound to ctx
+func (conn *APIConnection) GetTransactionsBetweenContext(ctx context.Context, accountID string, from, to time.Time) ([]Transaction, error) {
+	it := conn.Transactions(ctx, accountID, TransactionOptions{StartDate: from, EndDate: to})
+	var result []Transaction
+	for it.Next() {
+		result = append(result, it.Transaction())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
+// getTransactionPage requests a single page of transactions. The period
+// from start to end must not be longer than maxTransactionPeriod days
+func (conn *APIConnection) getTransactionPage(ctx context.Context, accountID string, start, end time.Time, index, length int) (transactions, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(apiTransactions, accountID)
+	r.params["startDate"] = start.Format(queryDateFormat)
+	r.params["endDate"] = end.Format(queryDateFormat)
+	r.params["index"] = strconv.Itoa(index)
+	r.params["length"] = strconv.Itoa(length)
 	var t transactions
-	resp, _ := conn.makeAPIRequest(r)
-	json.Unmarshal(resp, &t)
-	return t.Transactions
+	err := conn.requestJSON(r, &t)
+	return t, err
 }
 
 // NewAPIConnection creates an API connection for you
@@ -276,37 +474,45 @@ func (conn *APIConnection) GetTransactionsSince(accountid string, startDate stri
 // JSON file.
 //
 // The returned APIConnection struct contains all the
-// methods to communicate with the public Sbanken API
-func NewAPIConnection(cred Credentials) APIConnection {
+// methods to communicate with the public Sbanken API.
+// The behaviour of the connection can be adjusted by
+// passing one or more Options
+func NewAPIConnection(cred Credentials, options ...Option) APIConnection {
 	var conn APIConnection
 	conn.cred = cred
+	conn.auth = newTokenCache()
+	conn.baseURL = apiBase
+	conn.authURL = identityserver
+	conn.userAgent = defaultUserAgent
+	settings := Settings{conn: &conn, timeout: defaultTimeout}
+	for _, option := range options {
+		option(&settings)
+	}
+	conn.client = settings.httpClient()
 	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
-		token, err := conn.getToken()
-		if err != nil {
-			return []byte{}, err
+		ctx := r.ctx
+		if ctx == nil {
+			ctx = context.Background()
 		}
-		req, err := http.NewRequest("GET", r.target, nil)
+		token, err := conn.getToken(ctx)
 		if err != nil {
-			return []byte{}, fmt.Errorf("Failed to create request towards %s (%w)", r.target, err)
-		}
-		req.Header.Add("Authorization", "Bearer "+token)
-		req.Header.Add("User-Agent", "github.com/elzapp/go-sbanken")
-
-		for key, value := range r.headers {
-			req.Header.Add(key, value)
+			return []byte{}, err
 		}
-		log.Debugf("Requesting %+v using these headers: %+v", r, req.Header)
-		if len(r.params) > 0 {
-			q := req.URL.Query()
-			for key, value := range r.params {
-				q.Add(key, value)
+		resp, err := conn.doAPIRequest(ctx, r, token)
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			log.Debug("Token was rejected, re-authenticating")
+			resp.Body.Close()
+			conn.auth.invalidate(token)
+			token, err = conn.getToken(ctx)
+			if err != nil {
+				return []byte{}, err
 			}
-			req.URL.RawQuery = q.Encode()
+			resp, err = conn.doAPIRequest(ctx, r, token)
 		}
-		cli := &http.Client{Timeout: time.Second * 10}
-		resp, err := cli.Do(req)
 		if resp != nil && resp.StatusCode > 399 {
-			return []byte{}, fmt.Errorf("Got \"%s\" while requesting {%+v}", resp.Status, r)
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			return []byte{}, newHTTPError(resp.StatusCode, r.target, body)
 		}
 		if err == nil {
 			defer resp.Body.Close()
@@ -321,3 +527,41 @@ func NewAPIConnection(cred Credentials) APIConnection {
 	}
 	return conn
 }
+
+// doAPIRequest sends a single authenticated request to the API
+func (conn *APIConnection) doAPIRequest(ctx context.Context, r apirequest, token string) (*http.Response, error) {
+	var body io.Reader
+	if r.body != nil {
+		b, err := json.Marshal(r.body)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to encode request towards %s (%w)", r.target, err)
+		}
+		body = bytes.NewReader(b)
+	}
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.target, body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create request towards %s (%w)", r.target, err)
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	req.Header.Add("User-Agent", conn.userAgent)
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	for key, value := range r.headers {
+		req.Header.Add(key, value)
+	}
+	log.Debugf("Requesting %+v using these headers: %+v", r, req.Header)
+	if len(r.params) > 0 {
+		q := req.URL.Query()
+		for key, value := range r.params {
+			q.Add(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	return conn.client.Do(req)
+}
@@ -0,0 +1,166 @@
+package sbanken
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// reservationMatchDays is how many days the accounting date of an
+// archived transaction may differ from the date of its reservation
+const reservationMatchDays = 5
+
+// Match links a reserved transaction with the archived transaction
+// that replaced it when it was booked
+type Match struct {
+	Reservation Transaction
+	Archived    Transaction
+	// ReservationIndex and ArchivedIndex are the positions of the
+	// transactions in the slice given to MatchReservations
+	ReservationIndex int
+	ArchivedIndex    int
+}
+
+// PurchaseID returns the identifier that should be used for the
+// purchase, which is the one of the reservation, so that the purchase
+// keeps its identity when it is booked
+func (m Match) PurchaseID() string {
+	return m.Reservation.PurchaseID()
+}
+
+// PurchaseID returns a synthetic identifier for the purchase behind
+// the transaction. It is derived from the card transaction ID when
+// Sbanken supplies one, and otherwise from the amount, the
+// transaction date and the text, so it is the same for a reservation
+// and the archived transaction whenever these agree. Use
+// MatchReservations to link the ones that do not.
+func (t *Transaction) PurchaseID() string {
+	var key string
+	switch {
+	case t.CardDetails.TransactionID != "":
+		key = "card|" + t.CardDetails.TransactionID
+	default:
+		key = strings.Join([]string{"purchase", t.Amount.Decimal(), t.GetTransactionDate().Format(queryDateFormat), normalizeText(t.GetText())}, "|")
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// MatchReservations pairs reserved transactions with the archived
+// transactions that replaced them. Transactions are paired on the card
+// transaction ID when both have one, and otherwise on equal amount,
+// a transaction date no more than a few days apart and a similar text,
+// preferring exact matches. Each transaction is used in at most one
+// Match.
+func MatchReservations(txs []Transaction) []Match {
+	pairs := matchReservations(txs)
+	reservations := make([]int, 0, len(pairs))
+	for reservation := range pairs {
+		reservations = append(reservations, reservation)
+	}
+	sort.Ints(reservations)
+	var matches []Match
+	for _, reservation := range reservations {
+		archived := pairs[reservation]
+		matches = append(matches, Match{
+			Reservation:      txs[reservation],
+			Archived:         txs[archived],
+			ReservationIndex: reservation,
+			ArchivedIndex:    archived,
+		})
+	}
+	return matches
+}
+
+// Dedupe returns txs without the reservations that have been replaced
+// by an archived transaction in txs, keeping the order of the rest
+func Dedupe(txs []Transaction) []Transaction {
+	replaced := matchReservations(txs)
+	result := make([]Transaction, 0, len(txs)-len(replaced))
+	for i := range txs {
+		if _, ok := replaced[i]; !ok {
+			result = append(result, txs[i])
+		}
+	}
+	return result
+}
+
+// matchReservations returns the index of the archived transaction
+// matching each matched reservation, keyed by the reservation index
+func matchReservations(txs []Transaction) map[int]int {
+	var reserved, archived []int
+	for i := range txs {
+		if txs[i].IsReservation {
+			reserved = append(reserved, i)
+		} else {
+			archived = append(archived, i)
+		}
+	}
+	used := map[int]bool{}
+	matches := map[int]int{}
+	for _, strength := range []func(r, a *Transaction) bool{sameCardTransaction, samePurchase, similarPurchase} {
+		for _, ri := range reserved {
+			if _, ok := matches[ri]; ok {
+				continue
+			}
+			for _, ai := range archived {
+				if used[ai] || !strength(&txs[ri], &txs[ai]) {
+					continue
+				}
+				used[ai] = true
+				matches[ri] = ai
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func sameCardTransaction(r, a *Transaction) bool {
+	return r.CardDetails.TransactionID != "" && r.CardDetails.TransactionID == a.CardDetails.TransactionID
+}
+
+func samePurchase(r, a *Transaction) bool {
+	return r.Amount == a.Amount &&
+		sameDay(r.GetTransactionDate(), a.GetTransactionDate()) &&
+		normalizeText(r.GetText()) == normalizeText(a.GetText())
+}
+
+func similarPurchase(r, a *Transaction) bool {
+	if r.Amount != a.Amount {
+		return false
+	}
+	days := a.GetTransactionDate().Sub(r.GetTransactionDate()).Hours() / 24
+	if days < -1 || days > reservationMatchDays {
+		return false
+	}
+	rt, at := normalizeText(r.GetText()), normalizeText(a.GetText())
+	if rt == "" || at == "" {
+		return rt == at
+	}
+	return strings.HasPrefix(rt, at) || strings.HasPrefix(at, rt) || firstWord(rt) == firstWord(at)
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// normalizeText uppercases s and replaces everything but letters and
+// digits with single spaces
+func normalizeText(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func firstWord(s string) string {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
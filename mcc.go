@@ -0,0 +1,433 @@
+package sbanken
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MCC is a merchant category code as defined by ISO 18245, telling
+// what kind of business a card transaction was made with. The library
+// has a table of the common codes, with English and Norwegian names
+// and a grouping into spending categories:
+//
+//	for _, tx := range transactions {
+//		g := tx.MCC().Group()
+//		spent[g] = spent[g].Add(tx.Amount)
+//	}
+type MCC string
+
+// MCCGroup is a spending category grouping merchant category codes
+type MCCGroup string
+
+// The groups of merchant category codes
+const (
+	MCCGroceries     MCCGroup = "Groceries"
+	MCCAlcohol       MCCGroup = "Alcohol"
+	MCCRestaurants   MCCGroup = "Restaurants"
+	MCCTransport     MCCGroup = "Transport"
+	MCCFuel          MCCGroup = "Fuel"
+	MCCTravel        MCCGroup = "Travel"
+	MCCShopping      MCCGroup = "Shopping"
+	MCCHealth        MCCGroup = "Health"
+	MCCEntertainment MCCGroup = "Entertainment"
+	MCCUtilities     MCCGroup = "Utilities"
+	MCCHousing       MCCGroup = "Housing"
+	MCCServices      MCCGroup = "Services"
+	MCCEducation     MCCGroup = "Education"
+	MCCDonations     MCCGroup = "Donations"
+	MCCFinancial     MCCGroup = "Financial"
+	MCCCash          MCCGroup = "Cash"
+	MCCGovernment    MCCGroup = "Government"
+	MCCOther         MCCGroup = "Other"
+)
+
+var mccGroupNames = map[MCCGroup]string{
+	MCCGroceries:     "Dagligvarer",
+	MCCAlcohol:       "Alkohol",
+	MCCRestaurants:   "Restauranter",
+	MCCTransport:     "Transport",
+	MCCFuel:          "Drivstoff",
+	MCCTravel:        "Reise",
+	MCCShopping:      "Handel",
+	MCCHealth:        "Helse",
+	MCCEntertainment: "Fritid og underholdning",
+	MCCUtilities:     "Strøm og kommunikasjon",
+	MCCHousing:       "Bolig",
+	MCCServices:      "Tjenester",
+	MCCEducation:     "Utdanning",
+	MCCDonations:     "Gaver og organisasjoner",
+	MCCFinancial:     "Finans",
+	MCCCash:          "Kontanter",
+	MCCGovernment:    "Offentlig",
+	MCCOther:         "Annet",
+}
+
+// NorwegianName returns the name of the group in Norwegian
+func (g MCCGroup) NorwegianName() string {
+	if name, ok := mccGroupNames[g]; ok {
+		return name
+	}
+	return string(g)
+}
+
+type mccInfo struct {
+	name      string
+	norwegian string
+	group     MCCGroup
+}
+
+// mccRanges are the ranges of codes ISO 18245 assigns to single
+// airlines, car rental agencies and hotel chains
+var mccRanges = []struct {
+	from, to int
+	info     mccInfo
+}{
+	{3000, 3299, mccInfo{"Airlines", "Flyselskaper", MCCTravel}},
+	{3351, 3441, mccInfo{"Car Rental Agencies", "Bilutleie", MCCTravel}},
+	{3501, 3999, mccInfo{"Hotels, Motels and Resorts", "Hoteller", MCCTravel}},
+}
+
+var mccTable = map[MCC]mccInfo{
+	"0742": {"Veterinary Services", "Veterinærtjenester", MCCServices},
+	"0763": {"Agricultural Cooperatives", "Landbrukssamvirker", MCCServices},
+	"0780": {"Landscaping and Horticultural Services", "Hage- og anleggstjenester", MCCServices},
+	"1520": {"General Contractors, Residential and Commercial", "Byggentreprenører", MCCServices},
+	"1711": {"Heating, Plumbing and Air Conditioning Contractors", "Rørleggere og VVS", MCCServices},
+	"1731": {"Electrical Contractors", "Elektrikere", MCCServices},
+	"1740": {"Masonry, Stonework, Tile Setting and Plastering", "Murere og flisleggere", MCCServices},
+	"1750": {"Carpentry Contractors", "Snekkere", MCCServices},
+	"1761": {"Roofing, Siding and Sheet Metal Work", "Taktekkere og blikkenslagere", MCCServices},
+	"1799": {"Special Trade Contractors", "Andre håndverkere", MCCServices},
+	"2741": {"Miscellaneous Publishing and Printing", "Forlag og trykkerier", MCCServices},
+	"2842": {"Specialty Cleaning, Polishing and Sanitation Preparations", "Rengjøringsmidler", MCCShopping},
+	"4011": {"Railroads", "Jernbanefrakt", MCCTransport},
+	"4111": {"Local and Suburban Commuter Transportation, Including Ferries", "Lokaltransport og ferger", MCCTransport},
+	"4112": {"Passenger Railways", "Persontog", MCCTransport},
+	"4119": {"Ambulance Services", "Ambulansetjenester", MCCHealth},
+	"4121": {"Taxicabs and Limousines", "Taxi", MCCTransport},
+	"4131": {"Bus Lines", "Bussruter", MCCTransport},
+	"4214": {"Motor Freight Carriers and Trucking", "Godstransport", MCCServices},
+	"4215": {"Courier Services", "Budtjenester", MCCServices},
+	"4225": {"Public Warehousing and Storage", "Lager og oppbevaring", MCCServices},
+	"4411": {"Steamship and Cruise Lines", "Rederier og cruise", MCCTravel},
+	"4457": {"Boat Rentals and Leasing", "Båtutleie", MCCTravel},
+	"4468": {"Marinas, Marine Service and Supplies", "Båthavner", MCCTransport},
+	"4511": {"Airlines and Air Carriers", "Flyselskaper", MCCTravel},
+	"4582": {"Airports, Flying Fields and Airport Terminals", "Flyplasser", MCCTravel},
+	"4722": {"Travel Agencies and Tour Operators", "Reisebyråer", MCCTravel},
+	"4784": {"Tolls and Bridge Fees", "Bompenger", MCCTransport},
+	"4789": {"Transportation Services", "Transporttjenester", MCCTransport},
+	"4812": {"Telecommunication Equipment and Telephone Sales", "Telefoner og utstyr", MCCShopping},
+	"4814": {"Telecommunication Services", "Telefoni", MCCUtilities},
+	"4816": {"Computer Network and Information Services", "Internett og nettjenester", MCCUtilities},
+	"4821": {"Telegraph Services", "Telegraftjenester", MCCUtilities},
+	"4829": {"Wire Transfers and Money Orders", "Pengeoverføringer", MCCFinancial},
+	"4899": {"Cable, Satellite and Other Pay Television and Radio", "TV- og strømmetjenester", MCCEntertainment},
+	"4900": {"Utilities, Electric, Gas, Water and Sanitary", "Strøm, vann og renovasjon", MCCUtilities},
+	"5013": {"Motor Vehicle Supplies and New Parts", "Bildeler (engros)", MCCShopping},
+	"5021": {"Office and Commercial Furniture", "Kontormøbler", MCCShopping},
+	"5039": {"Construction Materials", "Byggematerialer", MCCShopping},
+	"5044": {"Photographic, Photocopy and Microfilm Equipment", "Foto- og kopiutstyr", MCCShopping},
+	"5045": {"Computers, Computer Peripheral Equipment and Software", "Datautstyr og programvare", MCCShopping},
+	"5046": {"Commercial Equipment", "Næringsutstyr", MCCShopping},
+	"5047": {"Medical, Dental, Ophthalmic and Hospital Equipment", "Medisinsk utstyr", MCCHealth},
+	"5051": {"Metal Service Centers and Offices", "Metallvarer", MCCShopping},
+	"5065": {"Electrical Parts and Equipment", "Elektriske deler", MCCShopping},
+	"5072": {"Hardware, Equipment and Supplies", "Jernvarer", MCCShopping},
+	"5074": {"Plumbing and Heating Equipment and Supplies", "VVS-utstyr", MCCShopping},
+	"5085": {"Industrial Supplies", "Industrivarer", MCCShopping},
+	"5094": {"Precious Stones and Metals, Watches and Jewelry", "Edelstener og smykker (engros)", MCCShopping},
+	"5111": {"Stationery, Office Supplies and Printing Paper", "Kontorrekvisita", MCCShopping},
+	"5122": {"Drugs, Drug Proprietaries and Druggist Sundries", "Legemidler (engros)", MCCHealth},
+	"5131": {"Piece Goods, Notions and Other Dry Goods", "Metervarer", MCCShopping},
+	"5137": {"Men's, Women's and Children's Uniforms", "Uniformer og arbeidsklær", MCCShopping},
+	"5139": {"Commercial Footwear", "Arbeidssko", MCCShopping},
+	"5169": {"Chemicals and Allied Products", "Kjemikalier", MCCShopping},
+	"5172": {"Petroleum and Petroleum Products", "Petroleumsprodukter", MCCFuel},
+	"5192": {"Books, Periodicals and Newspapers", "Bøker og aviser (engros)", MCCShopping},
+	"5193": {"Florists' Supplies, Nursery Stock and Flowers", "Blomster og planter (engros)", MCCShopping},
+	"5198": {"Paints, Varnishes and Supplies", "Maling og lakk", MCCShopping},
+	"5199": {"Nondurable Goods", "Forbruksvarer", MCCShopping},
+	"5200": {"Home Supply Warehouse Stores", "Byggevarehus", MCCShopping},
+	"5211": {"Lumber and Building Materials Stores", "Trelast og byggevarer", MCCShopping},
+	"5231": {"Glass, Paint and Wallpaper Stores", "Glass, maling og tapet", MCCShopping},
+	"5251": {"Hardware Stores", "Jernvarehandel", MCCShopping},
+	"5261": {"Nurseries and Lawn and Garden Supply Stores", "Hagesentre", MCCShopping},
+	"5271": {"Mobile Home Dealers", "Forhandlere av mobilhjem", MCCShopping},
+	"5300": {"Wholesale Clubs", "Grossistklubber", MCCShopping},
+	"5309": {"Duty Free Stores", "Taxfree", MCCShopping},
+	"5310": {"Discount Stores", "Lavprisbutikker", MCCShopping},
+	"5311": {"Department Stores", "Varehus", MCCShopping},
+	"5331": {"Variety Stores", "Billigvarehus", MCCShopping},
+	"5399": {"Miscellaneous General Merchandise", "Diverse varer", MCCShopping},
+	"5411": {"Grocery Stores and Supermarkets", "Dagligvarer", MCCGroceries},
+	"5422": {"Freezer and Locker Meat Provisioners", "Kjøtt- og fiskeforretninger", MCCGroceries},
+	"5441": {"Candy, Nut and Confectionery Stores", "Godteributikker", MCCGroceries},
+	"5451": {"Dairy Products Stores", "Meieriutsalg", MCCGroceries},
+	"5462": {"Bakeries", "Bakerier", MCCGroceries},
+	"5499": {"Miscellaneous Food Stores", "Andre matbutikker", MCCGroceries},
+	"5511": {"Car and Truck Dealers, New and Used", "Bilforhandlere", MCCTransport},
+	"5521": {"Car and Truck Dealers, Used Only", "Bruktbilforhandlere", MCCTransport},
+	"5531": {"Auto and Home Supply Stores", "Bil- og hjemutstyr", MCCShopping},
+	"5532": {"Automotive Tire Stores", "Dekkforhandlere", MCCTransport},
+	"5533": {"Automotive Parts and Accessories Stores", "Bildeler og tilbehør", MCCTransport},
+	"5541": {"Service Stations", "Bensinstasjoner", MCCFuel},
+	"5542": {"Automated Fuel Dispensers", "Drivstoffautomater", MCCFuel},
+	"5551": {"Boat Dealers", "Båtforhandlere", MCCShopping},
+	"5561": {"Camper, Recreational and Utility Trailer Dealers", "Campingvogner og tilhengere", MCCShopping},
+	"5571": {"Motorcycle Shops and Dealers", "Motorsykkelforhandlere", MCCTransport},
+	"5592": {"Motor Home Dealers", "Bobilforhandlere", MCCShopping},
+	"5598": {"Snowmobile Dealers", "Snøscooterforhandlere", MCCShopping},
+	"5599": {"Miscellaneous Automotive, Aircraft and Farm Equipment Dealers", "Andre kjøretøyforhandlere", MCCTransport},
+	"5611": {"Men's and Boys' Clothing and Accessories Stores", "Herreklær", MCCShopping},
+	"5621": {"Women's Ready-to-Wear Stores", "Dameklær", MCCShopping},
+	"5631": {"Women's Accessory and Specialty Shops", "Dametilbehør", MCCShopping},
+	"5641": {"Children's and Infants' Wear Stores", "Barneklær", MCCShopping},
+	"5651": {"Family Clothing Stores", "Klesbutikker", MCCShopping},
+	"5655": {"Sports and Riding Apparel Stores", "Sportsklær", MCCShopping},
+	"5661": {"Shoe Stores", "Skobutikker", MCCShopping},
+	"5681": {"Furriers and Fur Shops", "Pelsbutikker", MCCShopping},
+	"5691": {"Men's and Women's Clothing Stores", "Klær", MCCShopping},
+	"5697": {"Tailors, Seamstresses, Mending and Alterations", "Skreddere", MCCServices},
+	"5698": {"Wig and Toupee Stores", "Parykker", MCCShopping},
+	"5699": {"Miscellaneous Apparel and Accessory Shops", "Klær og tilbehør", MCCShopping},
+	"5712": {"Furniture, Home Furnishings and Equipment Stores", "Møbler og interiør", MCCShopping},
+	"5713": {"Floor Covering Stores", "Gulvbelegg", MCCShopping},
+	"5714": {"Drapery, Window Covering and Upholstery Stores", "Gardiner og tekstiler", MCCShopping},
+	"5718": {"Fireplace, Fireplace Screens and Accessories Stores", "Peiser og tilbehør", MCCShopping},
+	"5719": {"Miscellaneous Home Furnishing Specialty Stores", "Interiørbutikker", MCCShopping},
+	"5722": {"Household Appliance Stores", "Hvitevarer", MCCShopping},
+	"5732": {"Electronics Stores", "Elektronikk", MCCShopping},
+	"5733": {"Music Stores, Musical Instruments, Pianos and Sheet Music", "Musikkinstrumenter", MCCShopping},
+	"5734": {"Computer Software Stores", "Programvare", MCCShopping},
+	"5735": {"Record Stores", "Platebutikker", MCCEntertainment},
+	"5811": {"Caterers", "Catering", MCCRestaurants},
+	"5812": {"Eating Places and Restaurants", "Restauranter", MCCRestaurants},
+	"5813": {"Drinking Places, Bars, Taverns, Nightclubs and Discotheques", "Barer og utesteder", MCCRestaurants},
+	"5814": {"Fast Food Restaurants", "Hurtigmat", MCCRestaurants},
+	"5815": {"Digital Goods, Books, Movies and Music", "Digitale medier", MCCEntertainment},
+	"5816": {"Digital Goods, Games", "Digitale spill", MCCEntertainment},
+	"5817": {"Digital Goods, Applications", "Apper", MCCEntertainment},
+	"5818": {"Digital Goods, Large Digital Goods Merchant", "Digitale varer", MCCEntertainment},
+	"5912": {"Drug Stores and Pharmacies", "Apotek", MCCHealth},
+	"5921": {"Package Stores, Beer, Wine and Liquor", "Vinmonopol og ølutsalg", MCCAlcohol},
+	"5931": {"Used Merchandise and Secondhand Stores", "Bruktbutikker", MCCShopping},
+	"5932": {"Antique Shops", "Antikviteter", MCCShopping},
+	"5940": {"Bicycle Shops", "Sykkelbutikker", MCCShopping},
+	"5941": {"Sporting Goods Stores", "Sportsbutikker", MCCShopping},
+	"5942": {"Book Stores", "Bokhandlere", MCCShopping},
+	"5943": {"Stationery, Office and School Supply Stores", "Papirhandel", MCCShopping},
+	"5944": {"Jewelry Stores, Watches, Clocks and Silverware Stores", "Gullsmeder og urmakere", MCCShopping},
+	"5945": {"Hobby, Toy and Game Shops", "Leker og hobby", MCCShopping},
+	"5946": {"Camera and Photographic Supply Stores", "Fotobutikker", MCCShopping},
+	"5947": {"Gift, Card, Novelty and Souvenir Shops", "Gavebutikker", MCCShopping},
+	"5948": {"Luggage and Leather Goods Stores", "Vesker og lærvarer", MCCShopping},
+	"5949": {"Sewing, Needlework, Fabric and Piece Goods Stores", "Stoff og garn", MCCShopping},
+	"5950": {"Glassware and Crystal Stores", "Glass og krystall", MCCShopping},
+	"5960": {"Direct Marketing, Insurance Services", "Direktesalg av forsikring", MCCFinancial},
+	"5961": {"Mail Order Houses", "Postordre", MCCShopping},
+	"5962": {"Direct Marketing, Travel", "Direktesalg av reiser", MCCTravel},
+	"5963": {"Door-to-Door Sales", "Dørsalg", MCCShopping},
+	"5964": {"Direct Marketing, Catalog Merchant", "Katalogsalg", MCCShopping},
+	"5965": {"Direct Marketing, Combination Catalog and Retail Merchant", "Katalog- og butikksalg", MCCShopping},
+	"5966": {"Direct Marketing, Outbound Telemarketing Merchant", "Telefonsalg", MCCShopping},
+	"5967": {"Direct Marketing, Inbound Teleservices Merchant", "Teletorg", MCCEntertainment},
+	"5968": {"Direct Marketing, Continuity and Subscription Merchant", "Abonnementer", MCCShopping},
+	"5969": {"Direct Marketing, Other Direct Marketers", "Annet direktesalg", MCCShopping},
+	"5970": {"Artist's Supply and Craft Shops", "Kunstnerrekvisita", MCCShopping},
+	"5971": {"Art Dealers and Galleries", "Kunsthandel og gallerier", MCCShopping},
+	"5972": {"Stamp and Coin Stores", "Frimerker og mynter", MCCShopping},
+	"5973": {"Religious Goods Stores", "Religiøse artikler", MCCShopping},
+	"5975": {"Hearing Aids, Sales, Service and Supplies", "Høreapparater", MCCHealth},
+	"5976": {"Orthopedic Goods and Prosthetic Devices", "Ortopediske artikler", MCCHealth},
+	"5977": {"Cosmetic Stores", "Parfymerier", MCCShopping},
+	"5978": {"Typewriter Stores, Sales, Service and Rentals", "Skrivemaskiner", MCCShopping},
+	"5983": {"Fuel Dealers, Fuel Oil, Wood, Coal and Liquefied Petroleum", "Brensel og fyringsolje", MCCUtilities},
+	"5992": {"Florists", "Blomsterbutikker", MCCShopping},
+	"5993": {"Cigar Stores and Stands", "Tobakksbutikker", MCCShopping},
+	"5994": {"News Dealers and Newsstands", "Kiosker", MCCShopping},
+	"5995": {"Pet Shops, Pet Food and Supplies", "Dyrebutikker", MCCShopping},
+	"5996": {"Swimming Pools, Sales and Service", "Svømmebasseng", MCCShopping},
+	"5997": {"Electric Razor Stores, Sales and Service", "Barbermaskiner", MCCShopping},
+	"5998": {"Tent and Awning Shops", "Telt og markiser", MCCShopping},
+	"5999": {"Miscellaneous and Specialty Retail Stores", "Andre spesialbutikker", MCCShopping},
+	"6010": {"Financial Institutions, Manual Cash Disbursements", "Kontantuttak i bank", MCCCash},
+	"6011": {"Financial Institutions, Automated Cash Disbursements", "Minibank", MCCCash},
+	"6012": {"Financial Institutions, Merchandise and Services", "Finansinstitusjoner", MCCFinancial},
+	"6051": {"Non-Financial Institutions, Foreign Currency, Money Orders and Travelers' Cheques", "Valuta og betalingsmidler", MCCFinancial},
+	"6211": {"Security Brokers and Dealers", "Verdipapirmeglere", MCCFinancial},
+	"6300": {"Insurance Sales, Underwriting and Premiums", "Forsikring", MCCFinancial},
+	"6513": {"Real Estate Agents and Managers, Rentals", "Eiendomsmeglere og utleie", MCCHousing},
+	"6540": {"Non-Financial Institutions, Stored Value Card Purchase and Load", "Påfylling av verdikort", MCCFinancial},
+	"7011": {"Hotels, Motels and Resorts", "Hoteller", MCCTravel},
+	"7012": {"Timeshares", "Timeshare", MCCTravel},
+	"7032": {"Sporting and Recreational Camps", "Leirsteder", MCCEntertainment},
+	"7033": {"Trailer Parks and Campgrounds", "Campingplasser", MCCTravel},
+	"7210": {"Laundry, Cleaning and Garment Services", "Vaskerier og renserier", MCCServices},
+	"7211": {"Laundries, Family and Commercial", "Vaskerier", MCCServices},
+	"7216": {"Dry Cleaners", "Renserier", MCCServices},
+	"7217": {"Carpet and Upholstery Cleaning", "Tepperens", MCCServices},
+	"7221": {"Photographic Studios", "Fotografer", MCCServices},
+	"7230": {"Barber and Beauty Shops", "Frisører", MCCServices},
+	"7251": {"Shoe Repair Shops, Shoe Shine Parlors and Hat Cleaning Shops", "Skomakere", MCCServices},
+	"7261": {"Funeral Services and Crematories", "Begravelsesbyråer", MCCServices},
+	"7273": {"Dating and Escort Services", "Datingtjenester", MCCServices},
+	"7276": {"Tax Preparation Services", "Skatterådgivning", MCCServices},
+	"7277": {"Counseling Services, Debt, Marriage and Personal", "Rådgivning", MCCServices},
+	"7278": {"Buying and Shopping Services and Clubs", "Innkjøpstjenester", MCCServices},
+	"7296": {"Clothing Rental, Costumes, Uniforms and Formal Wear", "Klesutleie", MCCServices},
+	"7297": {"Massage Parlors", "Massasje", MCCHealth},
+	"7298": {"Health and Beauty Spas", "Spa og velvære", MCCHealth},
+	"7299": {"Miscellaneous Personal Services", "Andre personlige tjenester", MCCServices},
+	"7311": {"Advertising Services", "Reklame", MCCServices},
+	"7333": {"Commercial Photography, Art and Graphics", "Foto og grafisk design", MCCServices},
+	"7338": {"Quick Copy, Reproduction and Blueprinting Services", "Kopieringstjenester", MCCServices},
+	"7342": {"Exterminating and Disinfecting Services", "Skadedyrkontroll", MCCServices},
+	"7349": {"Cleaning, Maintenance and Janitorial Services", "Renhold", MCCServices},
+	"7361": {"Employment Agencies and Temporary Help Services", "Bemanningsbyråer", MCCServices},
+	"7372": {"Computer Programming, Data Processing and Integrated Systems Design", "Programmering og databehandling", MCCServices},
+	"7379": {"Computer Maintenance and Repair Services", "Datareparasjon", MCCServices},
+	"7392": {"Management, Consulting and Public Relations Services", "Konsulenttjenester", MCCServices},
+	"7393": {"Detective Agencies, Protective Agencies and Security Services", "Vakt og sikkerhet", MCCServices},
+	"7399": {"Business Services", "Forretningstjenester", MCCServices},
+	"7512": {"Automobile Rental Agency", "Bilutleie", MCCTravel},
+	"7513": {"Truck and Utility Trailer Rentals", "Utleie av lastebil og tilhenger", MCCTransport},
+	"7519": {"Motor Home and Recreational Vehicle Rentals", "Utleie av bobil", MCCTravel},
+	"7523": {"Parking Lots and Garages", "Parkering", MCCTransport},
+	"7531": {"Automotive Body Repair Shops", "Karosseriverksteder", MCCTransport},
+	"7534": {"Tire Retreading and Repair Shops", "Dekkservice", MCCTransport},
+	"7535": {"Automotive Paint Shops", "Billakkering", MCCTransport},
+	"7538": {"Automotive Service Shops", "Bilverksteder", MCCTransport},
+	"7542": {"Car Washes", "Bilvask", MCCTransport},
+	"7549": {"Towing Services", "Bilberging", MCCTransport},
+	"7622": {"Electronics Repair Shops", "Elektronikkreparasjon", MCCServices},
+	"7623": {"Air Conditioning and Refrigeration Repair Shops", "Kjøleteknikk", MCCServices},
+	"7629": {"Electrical and Small Appliance Repair Shops", "Reparasjon av småelektrisk", MCCServices},
+	"7631": {"Watch, Clock and Jewelry Repair", "Ur- og smykkereparasjon", MCCServices},
+	"7641": {"Furniture Reupholstery, Repair and Refinishing", "Møbelreparasjon", MCCServices},
+	"7692": {"Welding Repair", "Sveising", MCCServices},
+	"7699": {"Miscellaneous Repair Shops and Related Services", "Andre reparasjoner", MCCServices},
+	"7829": {"Motion Picture and Video Tape Production and Distribution", "Film- og videoproduksjon", MCCEntertainment},
+	"7832": {"Motion Picture Theaters", "Kino", MCCEntertainment},
+	"7841": {"Video Tape Rental Stores", "Videoutleie", MCCEntertainment},
+	"7911": {"Dance Halls, Studios and Schools", "Danseskoler", MCCEntertainment},
+	"7922": {"Theatrical Producers and Ticket Agencies", "Teater og billettsalg", MCCEntertainment},
+	"7929": {"Bands, Orchestras and Miscellaneous Entertainers", "Band og underholdere", MCCEntertainment},
+	"7932": {"Billiard and Pool Establishments", "Biljard", MCCEntertainment},
+	"7933": {"Bowling Alleys", "Bowling", MCCEntertainment},
+	"7941": {"Commercial Sports, Professional Sports Clubs, Athletic Fields and Sports Promoters", "Idrettsarrangementer", MCCEntertainment},
+	"7991": {"Tourist Attractions and Exhibits", "Turistattraksjoner", MCCEntertainment},
+	"7992": {"Public Golf Courses", "Golfbaner", MCCEntertainment},
+	"7993": {"Video Amusement Game Supplies", "Spillutstyr", MCCEntertainment},
+	"7994": {"Video Game Arcades and Establishments", "Spillehaller", MCCEntertainment},
+	"7995": {"Betting, Including Lottery Tickets, Casino Gaming Chips and Off-Track Betting", "Spill og lotteri", MCCEntertainment},
+	"7996": {"Amusement Parks, Circuses, Carnivals and Fortune Tellers", "Fornøyelsesparker", MCCEntertainment},
+	"7997": {"Membership Clubs, Sports, Recreation, Athletic, Country Clubs and Private Golf Courses", "Treningssentre og klubber", MCCEntertainment},
+	"7998": {"Aquariums, Seaquariums, Dolphinariums and Zoos", "Akvarier og dyreparker", MCCEntertainment},
+	"7999": {"Recreation Services", "Fritidstilbud", MCCEntertainment},
+	"8011": {"Doctors and Physicians", "Leger", MCCHealth},
+	"8021": {"Dentists and Orthodontists", "Tannleger", MCCHealth},
+	"8031": {"Osteopaths", "Osteopater", MCCHealth},
+	"8041": {"Chiropractors", "Kiropraktorer", MCCHealth},
+	"8042": {"Optometrists and Ophthalmologists", "Øyeleger og optometrister", MCCHealth},
+	"8043": {"Opticians, Optical Goods and Eyeglasses", "Optikere", MCCHealth},
+	"8049": {"Podiatrists and Chiropodists", "Fotterapeuter", MCCHealth},
+	"8050": {"Nursing and Personal Care Facilities", "Pleie og omsorg", MCCHealth},
+	"8062": {"Hospitals", "Sykehus", MCCHealth},
+	"8071": {"Medical and Dental Laboratories", "Medisinske laboratorier", MCCHealth},
+	"8099": {"Medical Services and Health Practitioners", "Helsetjenester", MCCHealth},
+	"8111": {"Legal Services and Attorneys", "Advokater", MCCServices},
+	"8211": {"Elementary and Secondary Schools", "Grunnskoler og videregående skoler", MCCEducation},
+	"8220": {"Colleges, Universities, Professional Schools and Junior Colleges", "Universiteter og høyskoler", MCCEducation},
+	"8241": {"Correspondence Schools", "Fjernundervisning", MCCEducation},
+	"8244": {"Business and Secretarial Schools", "Handelsskoler", MCCEducation},
+	"8249": {"Vocational and Trade Schools", "Yrkesskoler", MCCEducation},
+	"8299": {"Schools and Educational Services", "Undervisning og kurs", MCCEducation},
+	"8351": {"Child Care Services", "Barnehager og barnepass", MCCEducation},
+	"8398": {"Charitable and Social Service Organizations", "Veldedige organisasjoner", MCCDonations},
+	"8641": {"Civic, Social and Fraternal Associations", "Foreninger", MCCServices},
+	"8651": {"Political Organizations", "Politiske organisasjoner", MCCDonations},
+	"8661": {"Religious Organizations", "Religiøse organisasjoner", MCCDonations},
+	"8675": {"Automobile Associations", "Bilforeninger", MCCTransport},
+	"8699": {"Membership Organizations", "Medlemsorganisasjoner", MCCServices},
+	"8734": {"Testing Laboratories", "Testlaboratorier", MCCServices},
+	"8911": {"Architectural, Engineering and Surveying Services", "Arkitekter og ingeniører", MCCServices},
+	"8931": {"Accounting, Auditing and Bookkeeping Services", "Regnskap og revisjon", MCCServices},
+	"8999": {"Professional Services", "Profesjonelle tjenester", MCCServices},
+	"9211": {"Court Costs, Including Alimony and Child Support", "Rettsgebyrer", MCCGovernment},
+	"9222": {"Fines", "Bøter", MCCGovernment},
+	"9223": {"Bail and Bond Payments", "Kausjon", MCCGovernment},
+	"9311": {"Tax Payments", "Skatter og avgifter", MCCGovernment},
+	"9399": {"Government Services", "Offentlige tjenester", MCCGovernment},
+	"9402": {"Postal Services, Government Only", "Posttjenester", MCCGovernment},
+	"9405": {"Intra-Government Purchases, Government Only", "Statlige innkjøp", MCCGovernment},
+	"9950": {"Intra-Company Purchases", "Internkjøp", MCCOther},
+}
+
+// MCC returns the merchant category code of a card transaction, or an
+// empty MCC if there is none
+func (t *Transaction) MCC() MCC {
+	return ParseMCC(t.CardDetails.MerchantCategoryCode)
+}
+
+// ParseMCC returns the MCC in s as four digits, adding leading zeros
+// that are left out or removing extra ones, so 742 and 05411 become
+// 0742 and 5411. An empty MCC is returned if s is not a code
+func ParseMCC(s string) MCC {
+	s = strings.TrimSpace(s)
+	if !isDigits(s) {
+		return ""
+	}
+	s = strings.TrimLeft(s, "0")
+	if len(s) > 4 {
+		return ""
+	}
+	return MCC(strings.Repeat("0", 4-len(s)) + s)
+}
+
+// Known tells if the code is in the table of the library
+func (m MCC) Known() bool {
+	_, ok := m.info()
+	return ok
+}
+
+// Name returns the English name of the category, or the code itself
+// if it is unknown
+func (m MCC) Name() string {
+	if info, ok := m.info(); ok {
+		return info.name
+	}
+	return string(m)
+}
+
+// NorwegianName returns the Norwegian name of the category, or the
+// code itself if it is unknown
+func (m MCC) NorwegianName() string {
+	if info, ok := m.info(); ok {
+		return info.norwegian
+	}
+	return string(m)
+}
+
+// Group returns the spending category of the code. Unknown and empty
+// codes are in MCCOther
+func (m MCC) Group() MCCGroup {
+	if info, ok := m.info(); ok {
+		return info.group
+	}
+	return MCCOther
+}
+
+func (m MCC) info() (mccInfo, bool) {
+	if info, ok := mccTable[m]; ok {
+		return info, true
+	}
+	if n, err := strconv.Atoi(string(m)); err == nil && len(m) == 4 {
+		for _, r := range mccRanges {
+			if n >= r.from && n <= r.to {
+				return r.info, true
+			}
+		}
+	}
+	return mccInfo{}, false
+}
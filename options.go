@@ -0,0 +1,83 @@
+package sbanken
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option adjusts an APIConnection, see NewAPIConnection. Options can
+// be combined into new ones:
+//
+//	func WithTestEnvironment() sbanken.Option {
+//		return func(s *sbanken.Settings) {
+//			sbanken.WithBaseURL("http://localhost:8080/api/v1")(s)
+//			sbanken.WithAuthURL("http://localhost:8080/token")(s)
+//		}
+//	}
+type Option func(settings *Settings)
+
+// Settings holds the connection being set up by NewAPIConnection,
+// along with the options that are only needed while doing so. It is
+// only changed through options
+type Settings struct {
+	conn       *APIConnection
+	client     *http.Client
+	timeout    time.Duration
+	timeoutSet bool
+}
+
+// httpClient returns the client the connection should use. A client
+// supplied with WithHTTPClient is copied rather than modified when a
+// timeout is also given.
+func (s *Settings) httpClient() *http.Client {
+	if s.client == nil {
+		return &http.Client{Timeout: s.timeout}
+	}
+	if s.timeoutSet {
+		cli := *s.client
+		cli.Timeout = s.timeout
+		return &cli
+	}
+	return s.client
+}
+
+// WithHTTPClient makes the connection use client for all requests,
+// both towards the identityserver and the API. Use this to reuse
+// connection pools, go through a proxy or add a custom RoundTripper.
+// The timeout of the client is kept, unless WithTimeout is also given.
+func WithHTTPClient(client *http.Client) Option {
+	return func(settings *Settings) {
+		settings.client = client
+	}
+}
+
+// WithTimeout sets the timeout for each request. The default
+// is 10 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(settings *Settings) {
+		settings.timeout = timeout
+		settings.timeoutSet = true
+	}
+}
+
+// WithBaseURL points the connection at another API endpoint, like
+// https://publicapi.sbanken.no/apibeta/api/v1 or a local test server
+func WithBaseURL(baseURL string) Option {
+	return func(settings *Settings) {
+		settings.conn.baseURL = baseURL
+	}
+}
+
+// WithAuthURL sets the URL used to request access tokens
+func WithAuthURL(authURL string) Option {
+	return func(settings *Settings) {
+		settings.conn.authURL = authURL
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request
+func WithUserAgent(userAgent string) Option {
+	return func(settings *Settings) {
+		settings.conn.userAgent = userAgent
+	}
+}
@@ -1,17 +1,22 @@
 package sbanken
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
-const payments = `https://publicapi.sbanken.no/apibeta/api/v1/Payments/`
+const payments = `/Payments/`
+const standingOrders = `/StandingOrders/`
 
 // Payment ...
 type Payment struct {
 	ID                     string   `json:"paymentId"`
 	RecipientAccountNumber string   `json:"recipientAccountNumber"`
-	Amount                 float64  `json:"amount"`
-	DueDate                string   `json:"dueDate"`
+	Amount                 Amount   `json:"amount"`
+	DueDate                Date     `json:"dueDate"`
 	KID                    string   `json:"kid"`
 	Text                   string   `json:"text"`
 	IsActive               bool     `json:"isActive"`
@@ -24,26 +29,222 @@ type Payment struct {
 	BeneficiaryName        string   `json:"beneficiaryName"`
 }
 
+// NewPayment describes a domestic payment from AccountID to
+// RecipientAccountNumber, to be created with CreatePayment.
+// Either KID or Text must be given
+type NewPayment struct {
+	AccountID              string
+	RecipientAccountNumber string
+	Amount                 Amount
+	DueDate                time.Time
+	KID                    string
+	Text                   string
+	BeneficiaryName        string
+}
+
+type newPaymentRequest struct {
+	RecipientAccountNumber string `json:"recipientAccountNumber"`
+	Amount                 Amount `json:"amount"`
+	DueDate                string `json:"dueDate"`
+	KID                    string `json:"kid,omitempty"`
+	Text                   string `json:"text,omitempty"`
+	BeneficiaryName        string `json:"beneficiaryName,omitempty"`
+	IsActive               bool   `json:"isActive"`
+}
+
+// Validate checks the recipient account number, the KID or text, the
+// amount and the due date before the payment is sent to Sbanken. The due date is
+// taken as the calendar day it falls on in Oslo. The returned error
+// matches ErrValidation
+func (p NewPayment) Validate() error {
+	return p.validate(time.Now())
+}
+
+func (p NewPayment) validate(now time.Time) error {
+	if p.AccountID == "" {
+		return fmt.Errorf("The account to pay from must be given: %w", ErrValidation)
+	}
+	if err := ValidateAccountNumber(p.RecipientAccountNumber); err != nil {
+		return err
+	}
+	if p.KID != "" {
+		if err := ValidateKID(p.KID); err != nil {
+			return err
+		}
+	} else if strings.TrimSpace(p.Text) == "" {
+		return fmt.Errorf("Payment must have a KID or a text: %w", ErrValidation)
+	}
+	if p.Amount <= 0 {
+		return fmt.Errorf("Payment amount must be positive, got %s: %w", p.Amount, ErrValidation)
+	}
+	if p.DueDate.IsZero() {
+		return fmt.Errorf("Payment must have a due date: %w", ErrValidation)
+	}
+	if osloDay(p.DueDate).Before(osloDay(now)) {
+		return fmt.Errorf("Due date %s is in the past: %w", osloDay(p.DueDate).Format(queryDateFormat), ErrValidation)
+	}
+	return nil
+}
+
+// StandingOrder is a recurring payment (fast oppdrag)
+type StandingOrder struct {
+	ID                  string `json:"standingOrderId"`
+	AccountID           string `json:"accountId"`
+	CreditAccountNumber string `json:"creditAccountNumber"`
+	DebitAccountNumber  string `json:"debitAccountNumber"`
+	Amount              Amount `json:"amount"`
+	Frequency           string `json:"frequency"`
+	StartDate           Date   `json:"startDate"`
+	NextDueDate         Date   `json:"nextDueDate"`
+	EndDate             Date   `json:"endDate"`
+	KID                 string `json:"cid"`
+	FreeTerms           string `json:"freeTerms"`
+	StandingOrderType   string `json:"standingOrderType"`
+	BeneficiaryName     string `json:"beneficiaryName"`
+}
+
+type standingOrderListResponse struct {
+	AvailableItems int64           `json:"availableItems"`
+	Items          []StandingOrder `json:"items"`
+	errorInformation
+}
+
+type paymentStatusRequest struct {
+	Status string `json:"status"`
+}
+
 type paymentListResponse struct {
 	AvailableItems int64     `json:"availableItems"`
 	Items          []Payment `json:"items"`
 	errorInformation
 }
 
-type parymentItemResponse struct {
+type paymentItemResponse struct {
 	Item Payment `json:"item"`
 	errorInformation
 }
 
 // GetPayments ...
 func (conn *APIConnection) GetPayments(accountID string) ([]Payment, error) {
-	r := newAPIRequest()
-	r.target = payments + accountID
+	return conn.GetPaymentsContext(context.Background(), accountID)
+}
+
+// GetPaymentsContext is like GetPayments, but the request is bound to ctx
+func (conn *APIConnection) GetPaymentsContext(ctx context.Context, accountID string) ([]Payment, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(payments + accountID)
 	var a paymentListResponse
-	resp, err := conn.makeAPIRequest(r)
-	if err != nil {
+	if err := conn.requestJSON(r, &a); err != nil {
 		return nil, err
 	}
-	json.Unmarshal(resp, &a)
 	return a.Items, nil
 }
+
+// GetStandingOrders returns the standing orders paid from accountID
+func (conn *APIConnection) GetStandingOrders(accountID string) ([]StandingOrder, error) {
+	return conn.GetStandingOrdersContext(context.Background(), accountID)
+}
+
+// GetStandingOrdersContext is like GetStandingOrders, but the request is bound to ctx
+func (conn *APIConnection) GetStandingOrdersContext(ctx context.Context, accountID string) ([]StandingOrder, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(standingOrders + accountID)
+	var a standingOrderListResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return nil, err
+	}
+	return a.Items, nil
+}
+
+// CreatePayment validates and submits a domestic payment, returning
+// the Payment as registered by Sbanken with its ID and Status
+func (conn *APIConnection) CreatePayment(ctx context.Context, payment NewPayment) (Payment, error) {
+	if err := payment.Validate(); err != nil {
+		return Payment{}, err
+	}
+	r := newAPIRequest(ctx)
+	r.method = http.MethodPost
+	r.target = conn.endpoint(payments + payment.AccountID)
+	r.body = newPaymentRequest{
+		RecipientAccountNumber: NormalizeAccountNumber(payment.RecipientAccountNumber),
+		Amount:                 payment.Amount,
+		DueDate:                osloDay(payment.DueDate).Format(queryDateFormat),
+		KID:                    payment.KID,
+		Text:                   payment.Text,
+		BeneficiaryName:        payment.BeneficiaryName,
+		IsActive:               true,
+	}
+	var a paymentItemResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return Payment{}, err
+	}
+	if a.Item.ID == "" {
+		return Payment{}, fmt.Errorf("Sbanken did not return the created payment from %s", r.target)
+	}
+	return a.Item, nil
+}
+
+// GetPayment returns a single payment on an account. The returned
+// error matches ErrNotFound if there is no such payment
+func (conn *APIConnection) GetPayment(accountID, paymentID string) (Payment, error) {
+	return conn.GetPaymentContext(context.Background(), accountID, paymentID)
+}
+
+// GetPaymentContext is like GetPayment, but the request is bound to ctx
+func (conn *APIConnection) GetPaymentContext(ctx context.Context, accountID, paymentID string) (Payment, error) {
+	r := newAPIRequest(ctx)
+	r.target = conn.endpoint(payments + accountID + "/" + paymentID)
+	var a paymentItemResponse
+	if err := conn.requestJSON(r, &a); err != nil {
+		return Payment{}, err
+	}
+	if a.Item.ID == "" {
+		return Payment{}, fmt.Errorf("Payment %s was not found: %w", paymentID, ErrNotFound)
+	}
+	return a.Item, nil
+}
+
+// UpdatePaymentStatus changes the status of a payment, for instance to
+// stop or resume it. The status must be one of the payment's
+// AllowedNewStatusTypes, otherwise an error matching ErrValidation is
+// returned without contacting Sbanken
+func (conn *APIConnection) UpdatePaymentStatus(ctx context.Context, accountID, paymentID, status string) (Payment, error) {
+	p, err := conn.GetPaymentContext(ctx, accountID, paymentID)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !p.allowsStatus(status) {
+		return Payment{}, fmt.Errorf("Payment %s can not change status from %s to %s, allowed are %v: %w", paymentID, p.Status, status, p.AllowedNewStatusTypes, ErrValidation)
+	}
+	r := newAPIRequest(ctx)
+	r.method = http.MethodPut
+	r.target = conn.endpoint(payments + accountID + "/" + paymentID)
+	r.body = paymentStatusRequest{Status: status}
+	var a paymentItemResponse
+	if err := conn.requestAction(r, &a); err != nil {
+		return Payment{}, err
+	}
+	if a.Item.ID == "" {
+		p.Status = status
+		return p, nil
+	}
+	return a.Item, nil
+}
+
+// CancelPayment deletes a payment that has not been paid yet
+func (conn *APIConnection) CancelPayment(ctx context.Context, accountID, paymentID string) error {
+	r := newAPIRequest(ctx)
+	r.method = http.MethodDelete
+	r.target = conn.endpoint(payments + accountID + "/" + paymentID)
+	var a paymentItemResponse
+	return conn.requestAction(r, &a)
+}
+
+func (p Payment) allowsStatus(status string) bool {
+	for _, allowed := range p.AllowedNewStatusTypes {
+		if strings.EqualFold(allowed, status) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,16 @@
 package sbanken
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -90,7 +99,7 @@ func TestGetTransactions(t *testing.T) {
 			}
 			],
 			"errorType": "System",
-			"isError": true,
+			"isError": false,
 			"errorMessage": "string",
 			"traceId": "string"
 		  }`), nil
@@ -99,14 +108,14 @@ func TestGetTransactions(t *testing.T) {
 	if len(transactions) != 2 {
 		t.Errorf("Expected number of returned transactions to be 2, got %d", len(transactions))
 	}
-	if transactions[0].AccountingDate != "2019-10-14T00:00:00" {
+	if transactions[0].AccountingDate.Format(dateFormat) != "2019-10-14T00:00:00" {
 		t.Errorf("Expected accounting date to be 2019-10-14T00:00:00, got %s", transactions[0].AccountingDate)
 	}
-	if transactions[0].GetAccountingDate().Unix() != 1571011200 {
-		t.Errorf("Expected accounting date to be %d, got %d", 1571011200, transactions[0].GetAccountingDate().Unix())
+	if transactions[0].GetAccountingDate().Unix() != 1571004000 {
+		t.Errorf("Expected accounting date to be %d, got %d", 1571004000, transactions[0].GetAccountingDate().Unix())
 	}
-	if transactions[0].GetInterestDate().Unix() != 1571097600 {
-		t.Errorf("Expected interest date to be %d, got %d", 1571097600, transactions[0].GetInterestDate().Unix())
+	if transactions[0].GetInterestDate().Unix() != 1571090400 {
+		t.Errorf("Expected interest date to be %d, got %d", 1571090400, transactions[0].GetInterestDate().Unix())
 	}
 	if transactions[0].CardDetails.CardNumber != "*3100" {
 		t.Errorf("Expected cardNumber to be %s, but got %s", "*3100", transactions[0].CardDetails.CardNumber)
@@ -134,7 +143,7 @@ const efakturaList = `{
 	  }
 	],
 	"errorType": "System",
-	"isError": true,
+	"isError": false,
 	"errorMessage": "string",
 	"traceId": "string"
   }`
@@ -156,7 +165,7 @@ const singleEFaktura = `{
 	  "issuerName": "Telenor"
 	},
 	"errorType": "System",
-	"isError": true,
+	"isError": false,
 	"errorMessage": "string",
 	"traceId": "string"
   }`
@@ -204,7 +213,10 @@ func TestGetSingleEFaktura(t *testing.T) {
 		}
 		return []byte(singleEFaktura), nil
 	}
-	efaktura := conn.GetEFaktura("XYZXYZ")
+	efaktura, err := conn.GetEFaktura("XYZXYZ")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
 
 	if efaktura.EFakturaID != "XXXYZXYZ" {
 		t.Errorf("Expected efaktura id to be XXXYZXYZ, got %s", efaktura.EFakturaID)
@@ -214,8 +226,16 @@ func TestGetSingleEFaktura(t *testing.T) {
 	}
 }
 
+func mustParseDate(s string) Date {
+	d, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
 func TestGetPurchaseDateWithFallback(t *testing.T) {
-	tx := Transaction{AccountingDate: "2020-03-01T00:00:00"}
+	tx := Transaction{AccountingDate: mustParseDate("2020-03-01T00:00:00")}
 	expect := "2020-03-01"
 	got := tx.GetTransactionDate().Format(time.RFC3339)[0:10]
 	if got != expect {
@@ -224,9 +244,9 @@ func TestGetPurchaseDateWithFallback(t *testing.T) {
 	}
 }
 func TestGetPurchaseDateFromCardDetails(t *testing.T) {
-	tx := Transaction{AccountingDate: "2020-03-01T00:00:00"}
+	tx := Transaction{AccountingDate: mustParseDate("2020-03-01T00:00:00")}
 
-	tx.CardDetails.PurchaseDate = "2020-03-05T00:00:00"
+	tx.CardDetails.PurchaseDate = mustParseDate("2020-03-05T00:00:00")
 	expect := "2020-03-05"
 	got := tx.GetTransactionDate().Format(time.RFC3339)[0:10]
 	if got != expect {
@@ -237,7 +257,7 @@ func TestGetPurchaseDateFromCardDetails(t *testing.T) {
 
 func TestGetPurchaseDateFromText(t *testing.T) {
 	tx := Transaction{
-		AccountingDate: "2020-03-01T00:00:00",
+		AccountingDate: mustParseDate("2020-03-01T00:00:00"),
 		Text:           "28.02 REMA KALMARHUSE JON SMØRSGT  BERGEN",
 	}
 	expect := "2020-02-28"
@@ -250,7 +270,7 @@ func TestGetPurchaseDateFromText(t *testing.T) {
 
 func TestGetPurchaseDateFromTextNewyear(t *testing.T) {
 	tx := Transaction{
-		AccountingDate: "2020-01-01T00:00:00",
+		AccountingDate: mustParseDate("2020-01-01T00:00:00"),
 		Text:           "31.12 REMA KALMARHUSE JON SMØRSGT  BERGEN",
 	}
 	expect := "2019-12-31"
@@ -262,7 +282,7 @@ func TestGetPurchaseDateFromTextNewyear(t *testing.T) {
 }
 func TestGetPurchaseDateFromTextSameday(t *testing.T) {
 	tx := Transaction{
-		AccountingDate: "2020-01-01T00:00:00",
+		AccountingDate: mustParseDate("2020-01-01T00:00:00"),
 		Text:           "01.01 REMA KALMARHUSE JON SMØRSGT  BERGEN",
 	}
 	expect := "2020-01-01"
@@ -275,7 +295,7 @@ func TestGetPurchaseDateFromTextSameday(t *testing.T) {
 
 func TestGetPurchaseDateFromTextCreditCardNoDetails(t *testing.T) {
 	tx := Transaction{
-		AccountingDate: "2021-03-23T00:00:00",
+		AccountingDate: mustParseDate("2021-03-23T00:00:00"),
 		Text:           "*1234 22.03 NOK 49.30 EXTRA NESTTUN 837625 KURS: 1.0000",
 	}
 	expect := "2021-03-22"
@@ -346,3 +366,972 @@ func TestGetTextCreditCardWithoutCardDetails(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGetAccountsContextPassesContext(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "marker")
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.ctx.Value(ctxKey{}) != "marker" {
+			t.Errorf("Expected the request to carry the callers context")
+		}
+		return []byte(`{"items": []}`), nil
+	}
+	conn.GetAccountsContext(ctx)
+}
+
+func TestCancelledContextStopsTokenRequest(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := conn.GetAccountsContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	if conn.HasToken() {
+		t.Errorf("Expected a new connection to have no token")
+	}
+	conn.auth.token = "abc"
+	conn.auth.expires = time.Now().Add(time.Hour)
+	if !conn.HasToken() {
+		t.Errorf("Expected token valid for an hour to be usable")
+	}
+	conn.auth.expires = time.Now().Add(tokenRefreshMargin / 2)
+	if conn.HasToken() {
+		t.Errorf("Expected token about to expire to be refreshed")
+	}
+}
+
+func TestTokenInvalidateKeepsNewerToken(t *testing.T) {
+	c := newTokenCache()
+	c.token = "new"
+	c.invalidate("old")
+	if c.token != "new" {
+		t.Errorf("Expected invalidating a stale token to keep the current one, got %q", c.token)
+	}
+	c.invalidate("new")
+	if c.token != "" {
+		t.Errorf("Expected the token to be dropped, got %q", c.token)
+	}
+}
+
+func TestAPIErrorFromResponseBody(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return []byte(`{
+			"items": [],
+			"errorType": "Input",
+			"isError": true,
+			"errorMessage": "Invalid account",
+			"traceId": "trace-123"
+		  }`), nil
+	}
+	_, err := conn.GetTransactions("972219XXXXX")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 0 || strings.Contains(err.Error(), "200") {
+		t.Errorf("Expected an error from the body not to look like an HTTP status, got %v", err)
+	}
+	if apiErr.TraceID != "trace-123" {
+		t.Errorf("Expected traceId to be trace-123, got %s", apiErr.TraceID)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected error to match ErrValidation")
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("Did not expect error to match ErrNotFound")
+	}
+}
+
+func TestEmptyResponse(t *testing.T) {
+	conn := NewAPIConnection(Credentials{})
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return []byte{}, nil
+	}
+	if _, err := conn.GetAccounts(); err == nil {
+		t.Errorf("Expected an error for an empty list of accounts")
+	}
+	if err := conn.CancelPayment(context.Background(), "1", "2"); err != nil {
+		t.Errorf("Expected an empty response to a cancellation to be fine, got %v", err)
+	}
+}
+
+func TestInvalidTokenResponse(t *testing.T) {
+	for _, c := range []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `<html>maintenance</html>`},
+		{http.StatusOK, `{"access_token": "abc"`},
+		{http.StatusBadGateway, `<html>bad gateway</html>`},
+	} {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			fmt.Fprint(w, c.body)
+		})
+		srv := httptest.NewServer(mux)
+		conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"), WithHTTPClient(srv.Client()))
+		_, err := conn.GetAccounts()
+		srv.Close()
+		var apiErr *APIError
+		if c.status == http.StatusOK {
+			if err == nil || !strings.Contains(err.Error(), "decode token") {
+				t.Errorf("Expected a decoding error for %s, got %v", c.body, err)
+			}
+		} else if !errors.As(err, &apiErr) || apiErr.StatusCode != c.status || apiErr.Message != c.body {
+			t.Errorf("Expected an APIError with the body for %s, got %v", c.body, err)
+		}
+	}
+}
+
+func TestTokenErrorKeepsDescription(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_client", "error_description": "The client secret has expired"}`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"), WithHTTPClient(srv.Client()))
+	_, err := conn.GetAccounts()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected an unauthorized APIError, got %v", err)
+	}
+	if apiErr.ErrorType != "invalid_client" || apiErr.Message != "The client secret has expired (check that your secret is valid)" {
+		t.Errorf("Expected the description from the identityserver to be kept, got %q: %q", apiErr.ErrorType, apiErr.Message)
+	}
+}
+
+func TestAPIErrorFromHTTPStatus(t *testing.T) {
+	err := newHTTPError(404, "https://example.com", []byte(`{"isError":true,"traceId":"abc"}`))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected 404 to match ErrNotFound")
+	}
+	if err.TraceID != "abc" {
+		t.Errorf("Expected traceId to be abc, got %s", err.TraceID)
+	}
+	if !errors.Is(newHTTPError(429, "", nil), ErrRateLimited) {
+		t.Errorf("Expected 429 to match ErrRateLimited")
+	}
+	if !errors.Is(newHTTPError(401, "", nil), ErrUnauthorized) {
+		t.Errorf("Expected 401 to match ErrUnauthorized")
+	}
+}
+
+func TestInvalidJSONIsReported(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return []byte(`<html>`), nil
+	}
+	if _, err := conn.GetAccounts(); err == nil {
+		t.Errorf("Expected an error when the response is not JSON")
+	}
+}
+
+func newTestServer(handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var tokens int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokens, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, n)
+	})
+	mux.HandleFunc("/api/", handler)
+	return httptest.NewServer(mux), &tokens
+}
+
+func TestOptionsAndTokenReuse(t *testing.T) {
+	srv, tokens := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/Accounts" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("Expected User-Agent test-agent, got %s", r.Header.Get("User-Agent"))
+		}
+		fmt.Fprint(w, `{"items": [{"accountId": "1"}]}`)
+	})
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"),
+		WithHTTPClient(srv.Client()), WithUserAgent("test-agent"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := conn.GetAccounts(); err != nil {
+				t.Errorf("Unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(tokens); n != 1 {
+		t.Errorf("Expected exactly one token request, got %d", n)
+	}
+	if !conn.HasToken() {
+		t.Errorf("Expected the connection to have a token")
+	}
+}
+
+func TestWaitingForTokenRespectsContext(t *testing.T) {
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer close(release)
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"),
+		WithHTTPClient(srv.Client()), WithTimeout(time.Minute))
+
+	// The first caller hangs on the identityserver while holding the lock
+	go conn.GetAccounts()
+	for len(conn.auth.lock) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := conn.GetAccountsContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("Expected the caller to give up at its deadline, waited %s", waited)
+	}
+}
+
+func TestEmptyTokenIsAnError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"expires_in": 3600}`)
+	})
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no API request without a token")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"), WithHTTPClient(srv.Client()))
+	if _, err := conn.GetAccounts(); err == nil || !strings.Contains(err.Error(), "empty token") {
+		t.Errorf("Expected an empty token error, got %v", err)
+	}
+}
+
+func TestReauthenticateOnUnauthorized(t *testing.T) {
+	var calls int32
+	srv, tokens := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"items": []}`)
+	})
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"))
+	if _, err := conn.GetAccounts(); err != nil {
+		t.Errorf("Expected retry with new token to succeed, got %v", err)
+	}
+	if atomic.LoadInt32(tokens) != 2 || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected two token requests and two API calls, got %d and %d", *tokens, calls)
+	}
+}
+
+func TestUnauthorizedIsRetriedOnlyOnce(t *testing.T) {
+	srv, tokens := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"))
+	_, err := conn.GetAccounts()
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+	if n := atomic.LoadInt32(tokens); n != 2 {
+		t.Errorf("Expected two token requests, got %d", n)
+	}
+}
+
+func TestGetTransactionsBetweenPagesAndSplitsPeriods(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	var periods []string
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.params["index"] == "0" {
+			periods = append(periods, r.params["startDate"]+"/"+r.params["endDate"])
+		}
+		index, _ := strconv.Atoi(r.params["index"])
+		available := 0
+		if r.params["startDate"] == "2018-01-01" {
+			available = 2500
+		}
+		var items []string
+		for i := index; i < available && i < index+1000; i++ {
+			items = append(items, fmt.Sprintf(`{"transactionId": "%d"}`, i))
+		}
+		return []byte(fmt.Sprintf(`{"availableItems": %d, "items": [%s]}`, available, strings.Join(items, ","))), nil
+	}
+	from := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC)
+	txs, err := conn.GetTransactionsBetween("972219XXXXX", from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(txs) != 2500 {
+		t.Errorf("Expected 2500 transactions, got %d", len(txs))
+	}
+	expect := []string{"2018-01-01/2019-01-01", "2019-01-02/2020-01-02", "2020-01-03/2020-03-10"}
+	if strings.Join(periods, " ") != strings.Join(expect, " ") {
+		t.Errorf("Expected periods %v, got %v", expect, periods)
+	}
+}
+
+func TestGetTransactionsBetweenReturnsErrors(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return nil, newHTTPError(500, r.target, nil)
+	}
+	now := time.Now()
+	if _, err := conn.GetTransactionsBetween("972219XXXXX", now.AddDate(0, -1, 0), now); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if _, err := conn.GetTransactionsBetween("972219XXXXX", now, now.AddDate(0, -1, 0)); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for reversed period, got %v", err)
+	}
+}
+
+func TestGetTransactionsSinceHonorsStartDate(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	var starts []string
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		starts = append(starts, r.params["startDate"])
+		return []byte(`{"availableItems": 0, "items": []}`), nil
+	}
+	conn.GetTransactionsSince("972219XXXXX", "2021-06-01")
+	if len(starts) == 0 || starts[0] != "2021-06-01" {
+		t.Errorf("Expected first start date to be 2021-06-01, got %v", starts)
+	}
+}
+
+func TestTransactionIteratorUsesOsloDays(t *testing.T) {
+	conn := NewAPIConnection(Credentials{})
+	var period string
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		period = r.params["startDate"] + "/" + r.params["endDate"]
+		return []byte(`{"availableItems": 0, "items": []}`), nil
+	}
+	// Both are just after midnight in Oslo, but still the previous day in UTC
+	from := time.Date(2021, 3, 1, 23, 30, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 31, 22, 30, 0, 0, time.UTC)
+	it := conn.Transactions(context.Background(), "972219XXXXX", TransactionOptions{StartDate: from, EndDate: to})
+	for it.Next() {
+	}
+	if period != "2021-03-02/2021-04-01" {
+		t.Errorf("Expected the period 2021-03-02/2021-04-01, got %s", period)
+	}
+	// The same day in Oslo is a valid period, even if the times are reversed
+	it = conn.Transactions(context.Background(), "972219XXXXX", TransactionOptions{StartDate: to.Add(time.Hour), EndDate: to})
+	if it.Next(); it.Err() != nil {
+		t.Errorf("Expected no error for a single day, got %v", it.Err())
+	}
+}
+
+func TestTransactionIteratorIsLazy(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	requests := 0
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		requests++
+		index, _ := strconv.Atoi(r.params["index"])
+		length, _ := strconv.Atoi(r.params["length"])
+		var items []string
+		for i := index; i < 25 && i < index+length; i++ {
+			items = append(items, fmt.Sprintf(`{"transactionId": "%d"}`, i))
+		}
+		return []byte(fmt.Sprintf(`{"availableItems": 25, "items": [%s]}`, strings.Join(items, ","))), nil
+	}
+	it := conn.Transactions(context.Background(), "972219XXXXX", TransactionOptions{PageSize: 10})
+	if requests != 0 {
+		t.Errorf("Expected no requests before Next is called, got %d", requests)
+	}
+	for it.Next() {
+		if it.Transaction().TransactionID == "12" {
+			break
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected two pages to be fetched, got %d", requests)
+	}
+	count := 13
+	for it.Next() {
+		count++
+	}
+	if it.Err() != nil || count != 25 {
+		t.Errorf("Expected 25 transactions without error, got %d (%v)", count, it.Err())
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	srv, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/Transfers" {
+			t.Errorf("Expected POST to /api/Transfers, got %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", r.Header.Get("Content-Type"))
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["fromAccountId"] != "A" || body["toAccountId"] != "B" || body["amount"] != 100.5 {
+			t.Errorf("Unexpected request body %v", body)
+		}
+		fmt.Fprint(w, `{"isError": false, "traceId": "t1"}`)
+	})
+	defer srv.Close()
+	conn := NewAPIConnection(Credentials{}, WithBaseURL(srv.URL+"/api"), WithAuthURL(srv.URL+"/token"))
+	res, err := conn.Transfer(context.Background(), TransferRequest{FromAccountID: "A", ToAccountID: "B", Amount: 100*Krone + 50*Ore, Message: "Sparing"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res.TraceID != "t1" {
+		t.Errorf("Expected traceId t1, got %s", res.TraceID)
+	}
+}
+
+func TestTransferValidation(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		t.Errorf("Invalid transfers should not be sent")
+		return nil, nil
+	}
+	invalid := []TransferRequest{
+		{FromAccountID: "A", ToAccountID: "A", Amount: 1},
+		{FromAccountID: "A", ToAccountID: "B", Amount: -1},
+		{FromAccountID: "A", ToAccountID: "B", Amount: 1, Message: strings.Repeat("x", 31)},
+		{ToAccountID: "B", Amount: 1},
+	}
+	for _, transfer := range invalid {
+		if _, err := conn.Transfer(context.Background(), transfer); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected ErrValidation for %+v, got %v", transfer, err)
+		}
+	}
+}
+
+func TestPayEFaktura(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	status := "NEW"
+	paid := false
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.method == http.MethodPost {
+			if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/EFakturas" {
+				t.Errorf("PayEFaktura is calling wrong endpoint: %s", r.target)
+			}
+			paid = true
+			return []byte(`{"isError": false}`), nil
+		}
+		return []byte(fmt.Sprintf(`{"item": {"eFakturaId": "XYZXYZ", "status": "%s", "originalAmount": 100, "minimumAmount": 0}}`, status)), nil
+	}
+	pay := EFakturaPayRequest{EFakturaID: "XYZXYZ", AccountID: "972219XXXXX"}
+	if err := conn.PayEFaktura(context.Background(), pay); err != nil || !paid {
+		t.Errorf("Expected eFaktura to be paid, got %v", err)
+	}
+
+	paid = false
+	pay.PayOnlyMinimumAmount = true
+	if err := conn.PayEFaktura(context.Background(), pay); !errors.Is(err, ErrValidation) || paid {
+		t.Errorf("Expected ErrValidation when paying minimum amount without one, got %v", err)
+	}
+
+	status = "PROCESSED"
+	pay.PayOnlyMinimumAmount = false
+	if err := conn.PayEFaktura(context.Background(), pay); !errors.Is(err, ErrValidation) || paid {
+		t.Errorf("Expected ErrValidation when paying a processed eFaktura, got %v", err)
+	}
+}
+
+func TestGetEFakturaNotFound(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return nil, newHTTPError(404, r.target, nil)
+	}
+	if _, err := conn.GetEFaktura("XYZXYZ"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestListEFakturas(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		expect := map[string]string{"status": "PROCESSED", "startDate": "2021-03-01", "endDate": "2021-03-31", "length": "50"}
+		for key, value := range expect {
+			if r.params[key] != value {
+				t.Errorf("Expected %s to be %s, got %s", key, value, r.params[key])
+			}
+		}
+		if _, ok := r.params["index"]; ok {
+			t.Errorf("Expected index to be left out")
+		}
+		return []byte(efakturaList), nil
+	}
+	efakturas, err := conn.ListEFakturas(context.Background(), EFakturaFilter{
+		Status:    "PROCESSED",
+		StartDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC),
+		Length:    50,
+	})
+	if err != nil || len(efakturas) != 1 {
+		t.Errorf("Expected one eFaktura, got %d (%v)", len(efakturas), err)
+	}
+}
+
+func TestListEFakturasUsesOsloDays(t *testing.T) {
+	conn := NewAPIConnection(Credentials{})
+	var period string
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		period = r.params["startDate"] + "/" + r.params["endDate"]
+		return []byte(efakturaList), nil
+	}
+	// Both are midnight in Oslo, but still the previous day in UTC
+	from := time.Date(2021, 2, 28, 23, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 30, 22, 0, 0, 0, time.UTC)
+	if _, err := conn.ListEFakturas(context.Background(), EFakturaFilter{StartDate: from, EndDate: to}); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if period != "2021-03-01/2021-03-31" {
+		t.Errorf("Expected the period 2021-03-01/2021-03-31, got %s", period)
+	}
+}
+
+func TestNormalizeAccountNumber(t *testing.T) {
+	if n := NormalizeAccountNumber(" 9722.19 12345"); n != "97221912345" {
+		t.Errorf("Expected 97221912345, got %s", n)
+	}
+}
+
+func TestValidateAccountNumber(t *testing.T) {
+	for _, valid := range []string{"12345678903", "1234.56.78903"} {
+		if err := ValidateAccountNumber(valid); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"12345678904", "1234567890", "1234567890x"} {
+		if err := ValidateAccountNumber(invalid); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %s to be invalid, got %v", invalid, err)
+		}
+	}
+}
+
+func TestValidateKID(t *testing.T) {
+	for _, valid := range []string{"79927398713", "12343"} {
+		if err := ValidateKID(valid); err != nil {
+			t.Errorf("Expected %s to be valid, got %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"12345", "1", "12a43"} {
+		if err := ValidateKID(invalid); !errors.Is(err, ErrValidation) {
+			t.Errorf("Expected %s to be invalid, got %v", invalid, err)
+		}
+	}
+}
+
+func TestCreatePayment(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.method != http.MethodPost || r.target != "https://publicapi.sbanken.no/apibeta/api/v1/Payments/972219XXXXX" {
+			t.Errorf("CreatePayment is calling wrong endpoint: %s %s", r.method, r.target)
+		}
+		body := r.body.(newPaymentRequest)
+		if body.RecipientAccountNumber != "12345678903" || body.KID != "12343" {
+			t.Errorf("Unexpected request body %+v", body)
+		}
+		return []byte(`{"item": {"paymentId": "P1", "status": "Pending", "amount": 100}}`), nil
+	}
+	payment := NewPayment{
+		AccountID:              "972219XXXXX",
+		RecipientAccountNumber: "1234.56.78903",
+		Amount:                 100,
+		DueDate:                time.Now().AddDate(0, 0, 1),
+		KID:                    "12343",
+	}
+	created, err := conn.CreatePayment(context.Background(), payment)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if created.ID != "P1" || created.Status != "Pending" {
+		t.Errorf("Expected the created payment to be returned, got %+v", created)
+	}
+
+	payment.DueDate = time.Now().AddDate(0, 0, -2)
+	if _, err := conn.CreatePayment(context.Background(), payment); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for due date in the past, got %v", err)
+	}
+
+	payment.DueDate = time.Now().AddDate(0, 0, 1)
+	payment.KID = ""
+	if _, err := conn.CreatePayment(context.Background(), payment); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for a payment without KID or text, got %v", err)
+	}
+
+	payment.KID = "12343"
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return []byte(`{"item": {}}`), nil
+	}
+	if created, err := conn.CreatePayment(context.Background(), payment); err == nil {
+		t.Errorf("Expected an error when no payment is returned, got %+v", created)
+	}
+}
+
+func TestPaymentDueDateUsesOsloDays(t *testing.T) {
+	payment := NewPayment{AccountID: "972219XXXXX", RecipientAccountNumber: "12345678903", Amount: 100, Text: "Husleie"}
+	// 00:30 on 2 March in Oslo, which is still 1 March in UTC
+	now := time.Date(2021, 3, 1, 23, 30, 0, 0, time.UTC)
+	cases := []struct {
+		due   time.Time
+		valid bool
+	}{
+		{time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2021, 3, 1, 23, 15, 0, 0, time.UTC), true},
+		{time.Date(2021, 3, 2, 0, 0, 0, 0, Oslo), true},
+		{time.Date(2021, 3, 1, 0, 0, 0, 0, Oslo), false},
+	}
+	for _, c := range cases {
+		payment.DueDate = c.due
+		if err := payment.validate(now); (err == nil) != c.valid {
+			t.Errorf("Expected due date %s to be valid=%v, got %v", c.due, c.valid, err)
+		}
+	}
+}
+
+func TestUpdatePaymentStatus(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	var methods []string
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		methods = append(methods, r.method)
+		if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/Payments/972219XXXXX/P1" {
+			t.Errorf("Payment status is using wrong endpoint: %s", r.target)
+		}
+		if r.method == http.MethodPut {
+			return []byte(`{"item": {"paymentId": "P1", "status": "Stopped", "allowedNewStatusTypes": ["Active"]}}`), nil
+		}
+		return []byte(`{"item": {"paymentId": "P1", "status": "Active", "allowedNewStatusTypes": ["Stopped"]}}`), nil
+	}
+	p, err := conn.UpdatePaymentStatus(context.Background(), "972219XXXXX", "P1", "Stopped")
+	if err != nil || p.Status != "Stopped" {
+		t.Errorf("Expected payment to be stopped, got %+v (%v)", p, err)
+	}
+	methods = nil
+	if _, err := conn.UpdatePaymentStatus(context.Background(), "972219XXXXX", "P1", "Active"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected ErrValidation for status not allowed, got %v", err)
+	}
+	if len(methods) != 1 {
+		t.Errorf("Expected disallowed status change not to be sent, got %v", methods)
+	}
+}
+
+func TestCancelPayment(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.method != http.MethodDelete {
+			t.Errorf("Expected DELETE, got %s", r.method)
+		}
+		return nil, nil
+	}
+	if err := conn.CancelPayment(context.Background(), "972219XXXXX", "P1"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/Accounts/972219XXXXX" {
+			t.Errorf("GetAccount is calling wrong endpoint: %s", r.target)
+		}
+		return []byte(`{"item": {"accountId": "972219XXXXX", "balance": 100}}`), nil
+	}
+	account, err := conn.GetAccountContext(context.Background(), "972219XXXXX")
+	if err != nil || account.AccountID != "972219XXXXX" {
+		t.Errorf("Expected account 972219XXXXX, got %+v (%v)", account, err)
+	}
+}
+
+func TestGetCardNotFound(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/Cards/C1" {
+			t.Errorf("GetCard is calling wrong endpoint: %s", r.target)
+		}
+		return []byte(`{"item": null}`), nil
+	}
+	if _, err := conn.GetCardContext(context.Background(), "C1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGetCustomer(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/Customers" {
+			t.Errorf("GetCustomer is calling wrong endpoint: %s", r.target)
+		}
+		return []byte(`{
+			"item": {
+				"customerId": "01017012345",
+				"firstName": "Kari",
+				"lastName": "Nordmann",
+				"emailAddress": "kari@example.com",
+				"dateOfBirth": "1970-01-01T00:00:00",
+				"postalAddress": {"addressLine1": "Storgata 1", "zipCode": "5003", "city": "BERGEN", "country": "NO"},
+				"phoneNumbers": [{"countryCode": "47", "number": "12345678"}]
+			},
+			"isError": false
+		}`), nil
+	}
+	c, err := conn.GetCustomerContext(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if c.Name() != "Kari Nordmann" {
+		t.Errorf("Expected name Kari Nordmann, got %s", c.Name())
+	}
+	if lines := strings.Join(c.PostalAddress.Lines(), "\n"); lines != "Storgata 1\n5003 BERGEN" {
+		t.Errorf("Unexpected address %q", lines)
+	}
+	if len(c.PhoneNumbers) != 1 || c.PhoneNumbers[0].Number != "12345678" {
+		t.Errorf("Unexpected phone numbers %+v", c.PhoneNumbers)
+	}
+}
+
+func TestGetStandingOrders(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		if r.target != "https://publicapi.sbanken.no/apibeta/api/v1/StandingOrders/972219XXXXX" {
+			t.Errorf("GetStandingOrders is calling wrong endpoint: %s", r.target)
+		}
+		return []byte(`{
+			"availableItems": 1,
+			"items": [{
+				"standingOrderId": "S1",
+				"amount": 1500,
+				"frequency": "Monthly",
+				"nextDueDate": "2021-04-20T00:00:00",
+				"creditAccountNumber": "12345678903",
+				"cid": "12343"
+			}]
+		}`), nil
+	}
+	orders, err := conn.GetStandingOrdersContext(context.Background(), "972219XXXXX")
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("Expected one standing order, got %d (%v)", len(orders), err)
+	}
+	if orders[0].KID != "12343" || orders[0].Frequency != "Monthly" {
+		t.Errorf("Unexpected standing order %+v", orders[0])
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]Amount{
+		"-16.410":     -1641,
+		"58.000":      5800,
+		"0.1":         10,
+		"0.005":       1,
+		"-0.005":      -1,
+		"1 234,56":    123456,
+		"1234.56 kr":  123456,
+		"1.5E+2":      15000,
+		"12345678901": 1234567890100,
+	}
+	for in, expect := range cases {
+		got, err := ParseAmount(in)
+		if err != nil || got != expect {
+			t.Errorf("ParseAmount(%q) = %d (%v), expected %d", in, got, err, expect)
+		}
+	}
+	for _, in := range []string{"12,3,4", "", "-", "+", ".", " kr", "99999999999999999", "-99999999999999999", "1e30"} {
+		if got, err := ParseAmount(in); err == nil {
+			t.Errorf("Expected an error for %q, got %d", in, got)
+		}
+	}
+	if got, err := ParseAmount("92233720368547757.99"); err != nil || got != 9223372036854775799 {
+		t.Errorf("Expected the largest amount to parse, got %d (%v)", got, err)
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	cases := map[Amount]string{
+		123456:    "1 234,56 kr",
+		-123456:   "-1 234,56 kr",
+		5:         "0,05 kr",
+		100000000: "1 000 000,00 kr",
+	}
+	for in, expect := range cases {
+		if got := in.String(); got != expect {
+			t.Errorf("Expected %q, got %q", expect, got)
+		}
+	}
+}
+
+func TestAmountJSONDoesNotDrift(t *testing.T) {
+	var tx struct {
+		Items []struct {
+			Amount Amount `json:"amount"`
+		} `json:"items"`
+	}
+	items := strings.TrimSuffix(strings.Repeat(`{"amount": 0.1},`, 1000), ",")
+	if err := json.Unmarshal([]byte(`{"items": [`+items+`]}`), &tx); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var sum Amount
+	for _, item := range tx.Items {
+		sum = sum.Add(item.Amount)
+	}
+	if sum != 100*Krone {
+		t.Errorf("Expected the sum to be exactly 100 kr, got %s", sum)
+	}
+	b, _ := json.Marshal(struct {
+		Amount Amount `json:"amount"`
+	}{-1641})
+	if string(b) != `{"amount":-16.41}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	cases := map[string]string{
+		"2019-03-06T00:00:00":      "2019-03-06T00:00:00+01:00",
+		"2019-03-12T20:30:21.730Z": "2019-03-12T21:30:21.73+01:00",
+		"2019-07-01T12:00:00.5":    "2019-07-01T12:00:00.5+02:00",
+		"2019-07-01":               "2019-07-01T00:00:00+02:00",
+	}
+	for in, expect := range cases {
+		d, err := ParseDate(in)
+		if err != nil || d.Format(time.RFC3339Nano) != expect {
+			t.Errorf("ParseDate(%q) = %s (%v), expected %s", in, d.Format(time.RFC3339Nano), err, expect)
+		}
+	}
+	var e EFaktura
+	if err := json.Unmarshal([]byte(`{"originalDueDate": "12.03.2019"}`), &e); err == nil {
+		t.Errorf("Expected an error for an unknown date format")
+	}
+	if err := json.Unmarshal([]byte(`{"originalDueDate": null, "updatedDueDate": ""}`), &e); err != nil || !e.OriginalDueDate.IsZero() {
+		t.Errorf("Expected missing dates to decode to the zero Date, got %v", err)
+	}
+}
+
+func TestEFakturaDatesAreParsed(t *testing.T) {
+	var cred Credentials
+	conn := NewAPIConnection(cred)
+	conn.makeAPIRequest = func(r apirequest) ([]byte, error) {
+		return []byte(singleEFaktura), nil
+	}
+	efaktura, _ := conn.GetEFaktura("XYZXYZ")
+	if efaktura.OriginalDueDate.Year() != 2019 || efaktura.OriginalDueDate.Location() != Oslo {
+		t.Errorf("Expected due date in 2019 in Oslo, got %s", efaktura.OriginalDueDate)
+	}
+}
+
+func TestMatchReservations(t *testing.T) {
+	txs := []Transaction{
+		{IsReservation: true, Amount: -5000, AccountingDate: mustParseDate("2021-03-22T00:00:00"), Text: "REMA 1000 NESTTUN"},
+		{IsReservation: true, Amount: -4930, AccountingDate: mustParseDate("2021-03-22T00:00:00"), Text: "EXTRA NESTTUN",
+			CardDetails: cardDetails{TransactionID: "123"}},
+		{IsReservation: true, Amount: -9900, AccountingDate: mustParseDate("2021-03-23T00:00:00"), Text: "KIWI"},
+		{Source: "Archive", Amount: -4930, AccountingDate: mustParseDate("2021-03-24T00:00:00"), Text: "*1234 22.03 NOK 49.30 EXTRA NESTTUN 837625 KURS: 1.0000",
+			CardDetails: cardDetails{TransactionID: "123"}},
+		{Source: "Archive", Amount: -5000, AccountingDate: mustParseDate("2021-03-24T00:00:00"), Text: "22.03 REMA 1000 NESTTUN BERGEN"},
+	}
+	matches := MatchReservations(txs)
+	if len(matches) != 2 {
+		t.Fatalf("Expected two matches, got %d", len(matches))
+	}
+	if matches[0].Archived.Amount != -5000 || matches[1].Archived.Amount != -4930 {
+		t.Errorf("Unexpected matches %+v", matches)
+	}
+	if txs[1].PurchaseID() != txs[3].PurchaseID() {
+		t.Errorf("Expected reservation and archived card transaction to share PurchaseID")
+	}
+	if txs[0].PurchaseID() == txs[4].PurchaseID() {
+		t.Errorf("Did not expect differing texts to give the same PurchaseID")
+	}
+	deduped := Dedupe(txs)
+	if len(deduped) != 3 || deduped[0].Text != "KIWI" {
+		t.Errorf("Expected only the unmatched reservation and the archived transactions, got %+v", deduped)
+	}
+}
+
+func TestTransactionMCC(t *testing.T) {
+	cases := []struct {
+		code      string
+		mcc       MCC
+		name      string
+		norwegian string
+		group     MCCGroup
+	}{
+		{"5411", "5411", "Grocery Stores and Supermarkets", "Dagligvarer", MCCGroceries},
+		{" 4112 ", "4112", "Passenger Railways", "Persontog", MCCTransport},
+		{"742", "0742", "Veterinary Services", "Veterinærtjenester", MCCServices},
+		{"05411", "5411", "Grocery Stores and Supermarkets", "Dagligvarer", MCCGroceries},
+		{"15411", "", "", "", MCCOther},
+		{"3058", "3058", "Airlines", "Flyselskaper", MCCTravel},
+		{"1234", "1234", "1234", "1234", MCCOther},
+		{"", "", "", "", MCCOther},
+		{"54x1", "", "", "", MCCOther},
+	}
+	for _, c := range cases {
+		var tx Transaction
+		tx.CardDetails.MerchantCategoryCode = c.code
+		mcc := tx.MCC()
+		if mcc != c.mcc || mcc.Name() != c.name || mcc.NorwegianName() != c.norwegian || mcc.Group() != c.group {
+			t.Errorf("Unexpected MCC for %q: %q %q %q %q", c.code, mcc, mcc.Name(), mcc.NorwegianName(), mcc.Group())
+		}
+	}
+	if MCCGroceries.NorwegianName() != "Dagligvarer" {
+		t.Errorf("Expected the Norwegian group name Dagligvarer, got %s", MCCGroceries.NorwegianName())
+	}
+	for code, info := range mccTable {
+		if ParseMCC(string(code)) != code || info.name == "" || info.norwegian == "" {
+			t.Errorf("Invalid MCC table entry %s: %+v", code, info)
+		}
+		if _, ok := mccGroupNames[info.group]; !ok {
+			t.Errorf("MCC %s has an unknown group %s", code, info.group)
+		}
+	}
+}
+
+func TestCustomOption(t *testing.T) {
+	local := func() Option {
+		return func(s *Settings) {
+			WithBaseURL("http://localhost:8080/api/v1")(s)
+			WithUserAgent("local")(s)
+		}
+	}
+	conn := NewAPIConnection(Credentials{}, local())
+	if conn.baseURL != "http://localhost:8080/api/v1" || conn.userAgent != "local" {
+		t.Errorf("Expected the combined option to apply both settings, got %s %s", conn.baseURL, conn.userAgent)
+	}
+}
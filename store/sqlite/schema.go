@@ -0,0 +1,93 @@
+package sqlite
+
+// migrations are applied in order, and the number of applied
+// migrations is kept in schema_version. Never change a migration
+// that has been released, add a new one instead.
+var migrations = []string{
+	`CREATE TABLE accounts (
+		account_id        TEXT PRIMARY KEY,
+		account_number    TEXT NOT NULL,
+		owner_customer_id TEXT NOT NULL,
+		name              TEXT NOT NULL,
+		account_type      TEXT NOT NULL,
+		available_ore     INTEGER NOT NULL,
+		balance_ore       INTEGER NOT NULL,
+		credit_limit_ore  INTEGER NOT NULL,
+		refreshed_at      TEXT NOT NULL
+	);
+	CREATE TABLE transactions (
+		account_id                    TEXT NOT NULL,
+		id                            TEXT NOT NULL,
+		transaction_id                TEXT NOT NULL,
+		purchase_id                   TEXT NOT NULL,
+		accounting_date               TEXT,
+		interest_date                 TEXT,
+		transaction_date              TEXT,
+		other_account_number          TEXT NOT NULL,
+		transaction_type              TEXT NOT NULL,
+		transaction_type_code         INTEGER NOT NULL,
+		transaction_type_text         TEXT NOT NULL,
+		is_reservation                INTEGER NOT NULL,
+		amount_ore                    INTEGER NOT NULL,
+		text                          TEXT NOT NULL,
+		source                        TEXT NOT NULL,
+		card_details_specified        INTEGER NOT NULL,
+		card_number                   TEXT NOT NULL,
+		card_currency_amount          INTEGER NOT NULL,
+		card_currency_rate            REAL NOT NULL,
+		merchant_category_code        TEXT NOT NULL,
+		merchant_category_description TEXT NOT NULL,
+		merchant_city                 TEXT NOT NULL,
+		merchant_name                 TEXT NOT NULL,
+		original_currency_code        TEXT NOT NULL,
+		purchase_date                 TEXT,
+		card_transaction_id           TEXT NOT NULL,
+		PRIMARY KEY (account_id, id)
+	);
+	CREATE INDEX transactions_accounting_date ON transactions (account_id, accounting_date);
+	CREATE TABLE cards (
+		card_id             TEXT PRIMARY KEY,
+		card_number         TEXT NOT NULL,
+		card_version_number TEXT NOT NULL,
+		account_number      TEXT NOT NULL,
+		customer_id         TEXT NOT NULL,
+		expiry_date         TEXT,
+		account_owner       TEXT NOT NULL,
+		status              TEXT NOT NULL,
+		card_type           TEXT NOT NULL,
+		product_code        TEXT NOT NULL
+	);
+	CREATE TABLE payments (
+		payment_id               TEXT PRIMARY KEY,
+		account_id               TEXT NOT NULL,
+		recipient_account_number TEXT NOT NULL,
+		amount_ore               INTEGER NOT NULL,
+		due_date                 TEXT,
+		kid                      TEXT NOT NULL,
+		text                     TEXT NOT NULL,
+		is_active                INTEGER NOT NULL,
+		status                   TEXT NOT NULL,
+		allowed_new_status_types TEXT NOT NULL,
+		status_details           TEXT NOT NULL,
+		product_type             TEXT NOT NULL,
+		payment_type             TEXT NOT NULL,
+		payment_number           INTEGER NOT NULL,
+		beneficiary_name         TEXT NOT NULL
+	);
+	CREATE TABLE efakturas (
+		efaktura_id           TEXT PRIMARY KEY,
+		issuer_id             TEXT NOT NULL,
+		efaktura_reference    TEXT NOT NULL,
+		document_type         TEXT NOT NULL,
+		status                TEXT NOT NULL,
+		kid                   TEXT NOT NULL,
+		original_due_date     TEXT,
+		original_amount_ore   INTEGER NOT NULL,
+		minimum_amount_ore    INTEGER NOT NULL,
+		notification_date     TEXT,
+		issuer_name           TEXT NOT NULL,
+		updated_due_date      TEXT,
+		updated_amount_ore    INTEGER NOT NULL,
+		credit_account_number TEXT NOT NULL
+	);`,
+}
@@ -0,0 +1,401 @@
+// Package sqlite mirrors accounts, transactions, cards, payments and
+// eFakturas from Sbanken into a local SQLite database, so that years of
+// history can be queried with SQL without going through the API.
+//
+// Amounts are stored as whole øre in the *_ore columns, and dates as
+// text like 2021-03-22T00:00:00 in Europe/Oslo time, so that the SQLite
+// date functions give the same day as the bank statement.
+//
+//	store, err := sqlite.Open("sbanken.db")
+//	...
+//	err = store.Refresh(ctx, &conn, sqlite.RefreshOptions{})
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+	sbsync "github.com/elzapp/go-sbanken/sync"
+	// Register the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const dateFormat = "2006-01-02T15:04:05"
+
+// Source is the part of *sbanken.APIConnection used by Refresh
+type Source interface {
+	sbsync.TransactionSource
+	GetAccountsContext(ctx context.Context) ([]sbanken.Account, error)
+	GetCardsContext(ctx context.Context) ([]sbanken.Card, error)
+	GetPaymentsContext(ctx context.Context, accountID string) ([]sbanken.Payment, error)
+	GetAllEFakturasContext(ctx context.Context) ([]sbanken.EFaktura, error)
+}
+
+// Store is a SQLite database holding the mirrored data
+type Store struct {
+	DB *sql.DB
+}
+
+// Open opens, or creates, the database at path and migrates it to
+// the latest schema
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New migrates db to the latest schema and returns a Store using it
+func New(db *sql.DB) (*Store, error) {
+	s := &Store{DB: db}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.DB.Close()
+}
+
+// SchemaVersion returns the number of migrations applied to the database
+func (s *Store) SchemaVersion() (int, error) {
+	var version int
+	err := s.DB.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+func (s *Store) migrate() error {
+	if _, err := s.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return fmt.Errorf("Failed to create schema_version: %w", err)
+	}
+	var version int
+	err := s.DB.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+	if err == sql.ErrNoRows {
+		if _, err := s.DB.Exec(`INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("Database schema version %d is newer than this library supports (%d)", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`UPDATE schema_version SET version = ?`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RefreshOptions adjusts what Refresh fetches
+type RefreshOptions struct {
+	// From is the first accounting date to fetch transactions for. By
+	// default Refresh starts Overlap before the newest stored
+	// transaction, or at the oldest stored reservation if that is
+	// earlier. New accounts are fetched InitialPeriod back. The whole
+	// day in Oslo is fetched, whatever the time of day
+	From time.Time
+	// To defaults to today
+	To time.Time
+	// Overlap defaults to sync.DefaultOverlap
+	Overlap time.Duration
+	// InitialPeriod defaults to sync.DefaultInitialPeriod
+	InitialPeriod time.Duration
+}
+
+// Refresh pulls accounts, transactions, cards, payments and eFakturas
+// from src and upserts them. Reservations in the refreshed period that
+// Sbanken no longer returns are deleted.
+func (s *Store) Refresh(ctx context.Context, src Source, opts RefreshOptions) error {
+	to := opts.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	accounts, err := src.GetAccountsContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.UpsertAccounts(ctx, accounts); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		from := opts.From
+		if from.IsZero() {
+			if from, err = s.refreshStart(ctx, account.AccountID, to, opts); err != nil {
+				return err
+			}
+		}
+		// The whole first day is fetched, so the reservations on it
+		// must be replaced too
+		from = startOfDay(from)
+		txs, err := src.GetTransactionsBetweenContext(ctx, account.AccountID, from, to)
+		if err != nil {
+			return err
+		}
+		if err := s.ReplaceTransactions(ctx, account.AccountID, from, txs); err != nil {
+			return err
+		}
+		payments, err := src.GetPaymentsContext(ctx, account.AccountID)
+		if err != nil {
+			return err
+		}
+		if err := s.UpsertPayments(ctx, account.AccountID, payments); err != nil {
+			return err
+		}
+	}
+	cards, err := src.GetCardsContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.UpsertCards(ctx, cards); err != nil {
+		return err
+	}
+	efakturas, err := src.GetAllEFakturasContext(ctx)
+	if err != nil {
+		return err
+	}
+	return s.UpsertEFakturas(ctx, efakturas)
+}
+
+// refreshStart returns where to start fetching transactions for an
+// account, going far enough back to see whether the stored reservations
+// are still there
+func (s *Store) refreshStart(ctx context.Context, accountID string, to time.Time, opts RefreshOptions) (time.Time, error) {
+	var newest, oldestReservation sql.NullString
+	err := s.DB.QueryRowContext(ctx, `SELECT
+		(SELECT MAX(accounting_date) FROM transactions WHERE account_id = ? AND is_reservation = 0),
+		(SELECT MIN(accounting_date) FROM transactions WHERE account_id = ? AND is_reservation = 1)`,
+		accountID, accountID).Scan(&newest, &oldestReservation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	initial := opts.InitialPeriod
+	if initial <= 0 {
+		initial = sbsync.DefaultInitialPeriod
+	}
+	overlap := opts.Overlap
+	if overlap <= 0 {
+		overlap = sbsync.DefaultOverlap
+	}
+	from := to.Add(-initial)
+	if newest.Valid {
+		t, err := time.ParseInLocation(dateFormat, newest.String, sbanken.Oslo)
+		if err != nil {
+			return time.Time{}, err
+		}
+		from = t.Add(-overlap)
+	}
+	if oldestReservation.Valid {
+		t, err := time.ParseInLocation(dateFormat, oldestReservation.String, sbanken.Oslo)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t.Before(from) {
+			from = t
+		}
+	}
+	return from, nil
+}
+
+// UpsertAccounts inserts or updates accounts
+func (s *Store) UpsertAccounts(ctx context.Context, accounts []sbanken.Account) error {
+	now := time.Now().In(sbanken.Oslo).Format(dateFormat)
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, a := range accounts {
+			_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO accounts
+				(account_id, account_number, owner_customer_id, name, account_type,
+				 available_ore, balance_ore, credit_limit_ore, refreshed_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				a.AccountID, a.AccountNumber, a.OwnerCustomerID, a.Name, a.AccountType,
+				int64(a.Available), int64(a.Balance), int64(a.CreditLimit), now)
+			if err != nil {
+				return fmt.Errorf("Failed to store account %s: %w", a.AccountID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// ReplaceTransactions upserts the transactions fetched for an account
+// from the accounting date from, first deleting the reservations in
+// that period, since they are replaced when they are booked
+func (s *Store) ReplaceTransactions(ctx context.Context, accountID string, from time.Time, txs []sbanken.Transaction) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM transactions
+			WHERE account_id = ? AND is_reservation = 1 AND (accounting_date IS NULL OR accounting_date >= ?)`,
+			accountID, from.In(sbanken.Oslo).Format(dateFormat))
+		if err != nil {
+			return err
+		}
+		return upsertTransactions(ctx, tx, accountID, txs)
+	})
+}
+
+// UpsertTransactions inserts or updates transactions on an account
+func (s *Store) UpsertTransactions(ctx context.Context, accountID string, txs []sbanken.Transaction) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return upsertTransactions(ctx, tx, accountID, txs)
+	})
+}
+
+func upsertTransactions(ctx context.Context, tx *sql.Tx, accountID string, txs []sbanken.Transaction) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO transactions
+		(account_id, id, transaction_id, purchase_id, accounting_date, interest_date, transaction_date,
+		 other_account_number, transaction_type, transaction_type_code, transaction_type_text,
+		 is_reservation, amount_ore, text, source, card_details_specified, card_number,
+		 card_currency_amount, card_currency_rate, merchant_category_code, merchant_category_description,
+		 merchant_city, merchant_name, original_currency_code, purchase_date, card_transaction_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	keys := transactionKeys(txs)
+	for i, t := range txs {
+		cd := t.CardDetails
+		_, err := stmt.ExecContext(ctx,
+			accountID, keys[i], t.TransactionID, t.PurchaseID(),
+			formatDate(t.AccountingDate), formatDate(t.InterestDate), formatTime(t.GetTransactionDate()),
+			t.OtherAccountNumber, t.TransactionType, t.TransactionTypeCode, t.TransactionTypeText,
+			t.IsReservation, int64(t.Amount), t.Text, t.Source, t.CardDetailsSpecified, cd.CardNumber,
+			int64(cd.CurrencyAmount), cd.CurrencyRate, cd.MerchantCategoryCode, cd.MerchantCategoryDescription,
+			cd.MerchantCity, cd.MerchantName, cd.OriginalCurrencyCode, formatDate(cd.PurchaseDate), cd.TransactionID)
+		if err != nil {
+			return fmt.Errorf("Failed to store transaction %s: %w", t.TransactionID, err)
+		}
+	}
+	return nil
+}
+
+// transactionKeys returns the primary key of each transaction. Booked
+// transactions are identified by their ID from Sbanken, reservations
+// by their PurchaseID, with a counter for identical purchases
+func transactionKeys(txs []sbanken.Transaction) []string {
+	keys := make([]string, len(txs))
+	count := map[string]int{}
+	for i, t := range txs {
+		key := "purchase-" + t.PurchaseID()
+		if !t.IsReservation && t.TransactionID != "" {
+			key = "tx-" + t.TransactionID
+		}
+		count[key]++
+		if count[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, count[key])
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// UpsertCards inserts or updates cards
+func (s *Store) UpsertCards(ctx context.Context, cards []sbanken.Card) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range cards {
+			_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO cards
+				(card_id, card_number, card_version_number, account_number, customer_id,
+				 expiry_date, account_owner, status, card_type, product_code)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				c.CardID, c.CardNumber, c.CardVersionNumber, c.AccountNumber, c.CustomerID,
+				formatDate(c.ExpiryDate), c.AccountOwner, c.Status, c.CardType, c.ProductCode)
+			if err != nil {
+				return fmt.Errorf("Failed to store card %s: %w", c.CardID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertPayments inserts or updates payments from an account
+func (s *Store) UpsertPayments(ctx context.Context, accountID string, payments []sbanken.Payment) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range payments {
+			_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO payments
+				(payment_id, account_id, recipient_account_number, amount_ore, due_date, kid, text,
+				 is_active, status, allowed_new_status_types, status_details, product_type,
+				 payment_type, payment_number, beneficiary_name)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				p.ID, accountID, p.RecipientAccountNumber, int64(p.Amount), formatDate(p.DueDate), p.KID, p.Text,
+				p.IsActive, p.Status, strings.Join(p.AllowedNewStatusTypes, ","), p.StatusDetails, p.ProductType,
+				p.PaymentType, p.PaymentNumber, p.BeneficiaryName)
+			if err != nil {
+				return fmt.Errorf("Failed to store payment %s: %w", p.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertEFakturas inserts or updates eFakturas
+func (s *Store) UpsertEFakturas(ctx context.Context, efakturas []sbanken.EFaktura) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, e := range efakturas {
+			_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO efakturas
+				(efaktura_id, issuer_id, efaktura_reference, document_type, status, kid,
+				 original_due_date, original_amount_ore, minimum_amount_ore, notification_date,
+				 issuer_name, updated_due_date, updated_amount_ore, credit_account_number)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				e.EFakturaID, e.IssuerID, e.EFakturaReference, e.DocumentType, e.Status, e.KID,
+				formatDate(e.OriginalDueDate), int64(e.OriginalAmount), int64(e.MinimumAmount), formatDate(e.NotificationDate),
+				e.IssuerName, formatDate(e.UpdatedDueDate), int64(e.UpdatedAmount), e.CreditAccountNumber)
+			if err != nil {
+				return fmt.Errorf("Failed to store eFaktura %s: %w", e.EFakturaID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// startOfDay returns the start of the day t is in, in Norwegian time
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.In(sbanken.Oslo).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, sbanken.Oslo)
+}
+
+// formatDate returns d as text, or NULL for the zero Date
+func formatDate(d sbanken.Date) interface{} {
+	return formatTime(d.Time)
+}
+
+func formatTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.In(sbanken.Oslo).Format(dateFormat)
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+	"github.com/elzapp/go-sbanken/internal/sbankentest"
+)
+
+func newSource(txs ...sbanken.Transaction) *sbankentest.Source {
+	return &sbankentest.Source{
+		Accounts:     []sbanken.Account{{AccountID: "A", AccountNumber: "12345678903", Name: "Brukskonto", Balance: 123456}},
+		Transactions: txs,
+		Cards:        []sbanken.Card{{CardID: "C1", CardNumber: "*1234"}},
+		Payments:     []sbanken.Payment{{ID: "P1", Amount: 10000, AllowedNewStatusTypes: []string{"Stopped", "Deleted"}}},
+		EFakturas:    []sbanken.EFaktura{{EFakturaID: "E1", IssuerName: "Telenor", OriginalAmount: 49900}},
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbanken-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := Open(filepath.Join(dir, "sbanken.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if v, err := store.SchemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d (%v)", len(migrations), v, err)
+	}
+
+	accounting := sbankentest.Date("2021-03-22T00:00:00")
+	reservation := sbanken.Transaction{IsReservation: true, Amount: -5000, AccountingDate: accounting, Text: "REMA 1000"}
+	booked := sbanken.Transaction{TransactionID: "T1", Source: "Archive", Amount: -5000, AccountingDate: accounting,
+		Text: "*1234 22.03 NOK 50.00 REMA 1000 Kurs: 1.0000"}
+	booked.CardDetails.MerchantCategoryCode = "5411"
+	source := newSource(reservation)
+	ctx := context.Background()
+	opts := RefreshOptions{From: accounting.AddDate(0, 0, -1)}
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	source.Transactions = []sbanken.Transaction{booked}
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var count, reservations int
+	var sum int64
+	var mcc string
+	err = store.DB.QueryRow(`SELECT COUNT(*), SUM(is_reservation), SUM(amount_ore), MAX(merchant_category_code) FROM transactions`).
+		Scan(&count, &reservations, &sum, &mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || reservations != 0 || sum != -5000 || mcc != "5411" {
+		t.Errorf("Expected only the booked transaction, got %d rows, %d reservations, sum %d, mcc %s", count, reservations, sum, mcc)
+	}
+	var day string
+	if err := store.DB.QueryRow(`SELECT date(accounting_date) FROM transactions`).Scan(&day); err != nil || day != "2021-03-22" {
+		t.Errorf("Expected SQLite to understand the stored date, got %q (%v)", day, err)
+	}
+	var balance int64
+	if err := store.DB.QueryRow(`SELECT balance_ore FROM accounts WHERE account_id = 'A'`).Scan(&balance); err != nil || balance != 123456 {
+		t.Errorf("Expected balance 123456, got %d (%v)", balance, err)
+	}
+	var allowed string
+	if err := store.DB.QueryRow(`SELECT allowed_new_status_types FROM payments`).Scan(&allowed); err != nil || allowed != "Stopped,Deleted" {
+		t.Errorf("Unexpected allowed status types %q (%v)", allowed, err)
+	}
+
+	// Reopening must not apply the migrations again
+	store.Close()
+	store, err = Open(filepath.Join(dir, "sbanken.db"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	store.Close()
+}
+
+func TestRefreshRemovesStaleReservations(t *testing.T) {
+	store, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	old := sbankentest.Date("2021-01-10T00:00:00")
+	accounting := sbankentest.Date("2021-03-22T00:00:00")
+	stale := sbanken.Transaction{IsReservation: true, Amount: -30000, AccountingDate: old, Text: "HOTELL BERGEN"}
+	booked := sbanken.Transaction{TransactionID: "T1", Source: "Archive", Amount: -5000, AccountingDate: accounting, Text: "REMA 1000"}
+	source := newSource(stale, booked)
+	ctx := context.Background()
+	opts := RefreshOptions{To: time.Date(2021, 3, 25, 12, 0, 0, 0, sbanken.Oslo)}
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	// The reservation is older than sbsync.DefaultOverlap before the newest
+	// booking, but must still be refreshed
+	source.Transactions = []sbanken.Transaction{booked}
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var reservations int
+	if err := store.DB.QueryRow(`SELECT COUNT(*) FROM transactions WHERE is_reservation = 1`).Scan(&reservations); err != nil {
+		t.Fatal(err)
+	}
+	if reservations != 0 {
+		t.Errorf("Expected the stale reservation to be removed, got %d reservations", reservations)
+	}
+}
+
+func TestRefreshFromMiddleOfDay(t *testing.T) {
+	store, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	reservation := sbanken.Transaction{IsReservation: true, Amount: -5000, AccountingDate: sbankentest.Date("2021-03-22T00:00:00"), Text: "REMA 1000"}
+	source := newSource(reservation)
+	ctx := context.Background()
+	opts := RefreshOptions{
+		From: time.Date(2021, 3, 22, 14, 33, 12, 0, sbanken.Oslo),
+		To:   time.Date(2021, 3, 25, 12, 0, 0, 0, sbanken.Oslo),
+	}
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if want := time.Date(2021, 3, 22, 0, 0, 0, 0, sbanken.Oslo); !source.From.Equal(want) {
+		t.Errorf("Expected the refresh to start at %s, got %s", want, source.From)
+	}
+	source.Transactions = nil
+	if err := store.Refresh(ctx, source, opts); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var reservations int
+	if err := store.DB.QueryRow(`SELECT COUNT(*) FROM transactions WHERE is_reservation = 1`).Scan(&reservations); err != nil {
+		t.Fatal(err)
+	}
+	if reservations != 0 {
+		t.Errorf("Expected the reservation on the first day to be removed, got %d reservations", reservations)
+	}
+}
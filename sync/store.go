@@ -0,0 +1,148 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// Cursor is the high-water mark kept for each account between syncs
+type Cursor struct {
+	// LastAccountingDate is the latest accounting date seen on a
+	// booked transaction
+	LastAccountingDate time.Time `json:"lastAccountingDate"`
+	// Seen holds the last seen version of the transactions that may
+	// still change, keyed by ID
+	Seen map[string]sbanken.Transaction `json:"seen"`
+	// Aliases maps the PurchaseID of booked transactions to the ID
+	// of the reservation they replaced
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// clone returns a copy of c that can be modified without touching
+// the maps held by a Store
+func (c Cursor) clone() Cursor {
+	n := Cursor{
+		LastAccountingDate: c.LastAccountingDate,
+		Seen:               make(map[string]sbanken.Transaction, len(c.Seen)),
+		Aliases:            make(map[string]string, len(c.Aliases)),
+	}
+	for id, tx := range c.Seen {
+		n.Seen[id] = tx
+	}
+	for natural, id := range c.Aliases {
+		n.Aliases[natural] = id
+	}
+	return n
+}
+
+// prune forgets booked transactions from before from, which will not
+// be fetched again
+func (c *Cursor) prune(from time.Time) {
+	for id, tx := range c.Seen {
+		if !tx.IsReservation && tx.GetAccountingDate().Before(from) {
+			delete(c.Seen, id)
+		}
+	}
+	for natural, id := range c.Aliases {
+		if _, ok := c.Seen[id]; !ok {
+			delete(c.Aliases, natural)
+		}
+	}
+}
+
+// Store persists a Cursor for each account
+type Store interface {
+	// Load returns the Cursor for accountID, or an empty Cursor if
+	// the account has not been synced before
+	Load(accountID string) (Cursor, error)
+	Save(accountID string, cursor Cursor) error
+}
+
+// MemoryStore keeps cursors in memory, for tests and for processes
+// that run continuously
+type MemoryStore struct {
+	mu      gosync.Mutex
+	cursors map[string]Cursor
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{cursors: map[string]Cursor{}}
+}
+
+// Load implements Store
+func (m *MemoryStore) Load(accountID string) (Cursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cursors[accountID].clone(), nil
+}
+
+// Save implements Store
+func (m *MemoryStore) Save(accountID string, cursor Cursor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursors[accountID] = cursor.clone()
+	return nil
+}
+
+// FileStore keeps the cursor for each account as a JSON file in Dir
+type FileStore struct {
+	Dir string
+}
+
+// NewFileStore returns a FileStore writing to dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+// Load implements Store
+func (f *FileStore) Load(accountID string) (Cursor, error) {
+	var c Cursor
+	b, err := ioutil.ReadFile(f.path(accountID))
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, fmt.Errorf("Failed to read cursor %s: %w", f.path(accountID), err)
+	}
+	return c, nil
+}
+
+// Save implements Store. The file is replaced atomically, so that a
+// crash while saving leaves the previous cursor in place
+func (f *FileStore) Save(accountID string, cursor Cursor) error {
+	b, err := json.MarshalIndent(cursor, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(f.Dir, accountID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(accountID))
+}
+
+func (f *FileStore) path(accountID string) string {
+	return filepath.Join(f.Dir, filepath.Base(accountID)+".json")
+}
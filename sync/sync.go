@@ -0,0 +1,267 @@
+// Package sync keeps track of which transactions have already been
+// seen on an account, so that a job running every few minutes only
+// has to deal with transactions that are new, changed or gone.
+//
+//	syncer := sync.New(&conn, sync.NewMemoryStore())
+//	events, err := syncer.Sync(ctx, accountID)
+//	for _, e := range events {
+//		switch e.Type {
+//		case sync.Added:
+//		case sync.Updated:
+//		case sync.Removed:
+//		}
+//	}
+//
+// Reservations that are booked are reported as an Updated event
+// keeping the ID of the reservation, and reservations that disappear
+// without being booked are reported as Removed.
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+)
+
+// DefaultOverlap is how far before the last seen accounting date each
+// sync starts, to pick up transactions that are booked late
+const DefaultOverlap = 14 * 24 * time.Hour
+
+// DefaultInitialPeriod is how far back the first sync of an account goes
+const DefaultInitialPeriod = 90 * 24 * time.Hour
+
+// TransactionSource fetches transactions for an account.
+// *sbanken.APIConnection implements it
+type TransactionSource interface {
+	GetTransactionsBetweenContext(ctx context.Context, accountID string, from, to time.Time) ([]sbanken.Transaction, error)
+}
+
+// EventType tells what happened to a transaction
+type EventType int
+
+// The kinds of events returned from Sync
+const (
+	Added EventType = iota + 1
+	Updated
+	Removed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is a change to a transaction since the previous sync
+type Event struct {
+	Type EventType
+	// ID is stable for the purchase, also when a reservation is booked
+	ID string
+	// Transaction is the current transaction, or the last seen
+	// version of a removed one
+	Transaction sbanken.Transaction
+	// Previous is the last seen version of an updated transaction
+	Previous *sbanken.Transaction
+}
+
+// Syncer fetches transactions and compares them with the Cursor kept
+// in the Store
+type Syncer struct {
+	Source TransactionSource
+	Store  Store
+	// Overlap defaults to DefaultOverlap
+	Overlap time.Duration
+	// InitialPeriod defaults to DefaultInitialPeriod
+	InitialPeriod time.Duration
+
+	now func() time.Time
+}
+
+// New returns a Syncer using the default periods
+func New(source TransactionSource, store Store) *Syncer {
+	return &Syncer{
+		Source:        source,
+		Store:         store,
+		Overlap:       DefaultOverlap,
+		InitialPeriod: DefaultInitialPeriod,
+		now:           time.Now,
+	}
+}
+
+// Sync fetches the transactions on accountID since shortly before the
+// last sync, returns what has changed and saves the new Cursor. If
+// fetching or saving fails, the saved Cursor is left untouched, so
+// that the same events are returned by the next Sync.
+func (s *Syncer) Sync(ctx context.Context, accountID string) ([]Event, error) {
+	cur, err := s.Store.Load(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load cursor for %s: %w", accountID, err)
+	}
+	cur = cur.clone()
+	to := s.clock()
+	from := s.windowStart(cur, to)
+	txs, err := s.Source.GetTransactionsBetweenContext(ctx, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	ids := assignIDs(cur, txs)
+
+	// Seen transactions within the window that were not returned
+	// again have either been booked or removed
+	fetched := map[string]bool{}
+	for _, id := range ids {
+		fetched[id] = true
+	}
+	var gone []string
+	for id, tx := range cur.Seen {
+		if !fetched[id] && (tx.IsReservation || !tx.GetAccountingDate().Before(from)) {
+			gone = append(gone, id)
+		}
+	}
+	sort.Strings(gone)
+	bookedAs := matchBooked(cur, gone, txs, ids)
+
+	var events []Event
+	booked := map[string]bool{}
+	for i, tx := range txs {
+		id := ids[i]
+		if reservationID, ok := bookedAs[i]; ok {
+			prev := cur.Seen[reservationID]
+			events = append(events, Event{Type: Updated, ID: reservationID, Transaction: tx, Previous: &prev})
+			cur.Aliases[id] = reservationID
+			cur.Seen[reservationID] = tx
+			booked[reservationID] = true
+			continue
+		}
+		prev, seen := cur.Seen[id]
+		if !seen {
+			events = append(events, Event{Type: Added, ID: id, Transaction: tx})
+		} else if fingerprint(prev) != fingerprint(tx) {
+			events = append(events, Event{Type: Updated, ID: id, Transaction: tx, Previous: &prev})
+		}
+		cur.Seen[id] = tx
+	}
+	for _, id := range gone {
+		if !booked[id] {
+			events = append(events, Event{Type: Removed, ID: id, Transaction: cur.Seen[id]})
+			delete(cur.Seen, id)
+		}
+	}
+
+	for _, tx := range txs {
+		if !tx.IsReservation && tx.GetAccountingDate().After(cur.LastAccountingDate) {
+			cur.LastAccountingDate = tx.GetAccountingDate()
+		}
+	}
+	cur.prune(s.windowStart(cur, to))
+	if err := s.Store.Save(accountID, cur); err != nil {
+		return nil, fmt.Errorf("Failed to save cursor for %s: %w", accountID, err)
+	}
+	return events, nil
+}
+
+// windowStart returns the first accounting date to fetch, going far
+// enough back to see whether the reservations seen so far are still there
+func (s *Syncer) windowStart(cur Cursor, to time.Time) time.Time {
+	if cur.LastAccountingDate.IsZero() {
+		initial := s.InitialPeriod
+		if initial <= 0 {
+			initial = DefaultInitialPeriod
+		}
+		return to.Add(-initial)
+	}
+	overlap := s.Overlap
+	if overlap <= 0 {
+		overlap = DefaultOverlap
+	}
+	from := cur.LastAccountingDate.Add(-overlap)
+	for _, tx := range cur.Seen {
+		date := tx.GetAccountingDate()
+		if tx.IsReservation && !date.IsZero() && date.Before(from) {
+			from = date
+		}
+	}
+	return from
+}
+
+func (s *Syncer) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// matchBooked finds the reservations among the gone transactions that
+// have been booked as one of the new transactions. It returns the ID of
+// the reservation, keyed by the index of the booked transaction in txs
+func matchBooked(cur Cursor, gone []string, txs []sbanken.Transaction, ids []string) map[int]string {
+	var pool []sbanken.Transaction
+	for _, id := range gone {
+		pool = append(pool, cur.Seen[id])
+	}
+	var unseen []int
+	for i, id := range ids {
+		if _, seen := cur.Seen[id]; !seen {
+			unseen = append(unseen, i)
+			pool = append(pool, txs[i])
+		}
+	}
+	bookedAs := map[int]string{}
+	for _, m := range sbanken.MatchReservations(pool) {
+		if m.ReservationIndex < len(gone) && m.ArchivedIndex >= len(gone) {
+			bookedAs[unseen[m.ArchivedIndex-len(gone)]] = gone[m.ReservationIndex]
+		}
+	}
+	return bookedAs
+}
+
+// naturalID identifies a transaction without looking at earlier syncs.
+// Booked transactions have an ID from Sbanken, which stays the same if
+// the text is corrected, but card transactions use the PurchaseID so
+// that they are linked to their reservation without an alias
+func naturalID(tx sbanken.Transaction) string {
+	if !tx.IsReservation && tx.TransactionID != "" && tx.CardDetails.TransactionID == "" {
+		return "tx-" + tx.TransactionID
+	}
+	return tx.PurchaseID()
+}
+
+// assignIDs returns the ID of each transaction, which is its naturalID
+// unless it has been linked to an earlier reservation. Identical
+// purchases get a counter appended to tell them apart
+func assignIDs(cur Cursor, txs []sbanken.Transaction) []string {
+	ids := make([]string, len(txs))
+	count := map[string]int{}
+	for i, tx := range txs {
+		id := naturalID(tx)
+		count[id]++
+		if count[id] > 1 {
+			id = fmt.Sprintf("%s#%d", id, count[id])
+		}
+		if alias, ok := cur.Aliases[id]; ok {
+			id = alias
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// fingerprint identifies the content of a transaction, so that
+// changes can be detected
+func fingerprint(tx sbanken.Transaction) string {
+	b, _ := json.Marshal(tx)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
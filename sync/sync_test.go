@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	sbanken "github.com/elzapp/go-sbanken"
+	"github.com/elzapp/go-sbanken/internal/sbankentest"
+)
+
+var date = sbankentest.Date
+
+var (
+	booked = sbanken.Transaction{TransactionID: "1", Source: "Archive", Amount: -12000,
+		AccountingDate: date("2021-03-20T00:00:00"), Text: "Til: BONNIER PUBLICA Betalt: 19.03.21"}
+	reserved = sbanken.Transaction{IsReservation: true, Amount: -5000,
+		AccountingDate: date("2021-03-22T00:00:00"), Text: "REMA 1000 NESTTUN"}
+	archived = sbanken.Transaction{TransactionID: "2", Source: "Archive", Amount: -5000,
+		AccountingDate: date("2021-03-24T00:00:00"), Text: "22.03 REMA 1000 NESTTUN BERGEN"}
+	cancelled = sbanken.Transaction{IsReservation: true, Amount: -30000,
+		AccountingDate: date("2021-03-24T00:00:00"), Text: "HOTELL BERGEN"}
+)
+
+func runScenario(t *testing.T, store Store) {
+	source := &sbankentest.Source{Transactions: []sbanken.Transaction{reserved, booked}}
+	syncer := New(source, store)
+	syncer.now = func() time.Time { return time.Date(2021, 3, 25, 12, 0, 0, 0, sbanken.Oslo) }
+	ctx := context.Background()
+
+	events, err := syncer.Sync(ctx, "A")
+	if err != nil || len(events) != 2 || events[0].Type != Added || events[1].Type != Added {
+		t.Fatalf("Expected two added transactions, got %v (%v)", events, err)
+	}
+	reservationID := events[0].ID
+
+	source.Transactions = []sbanken.Transaction{archived, booked, cancelled}
+	events, err = syncer.Sync(ctx, "A")
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Expected two events, got %v (%v)", events, err)
+	}
+	if events[0].Type != Updated || events[0].ID != reservationID || events[0].Transaction.IsReservation || events[0].Previous == nil {
+		t.Errorf("Expected the booked reservation to be updated keeping its ID, got %+v", events[0])
+	}
+	if events[1].Type != Added || events[1].Transaction.Text != cancelled.Text {
+		t.Errorf("Expected the new reservation to be added, got %+v", events[1])
+	}
+	if want := time.Date(2021, 3, 6, 0, 0, 0, 0, sbanken.Oslo); !source.From.Equal(want) {
+		t.Errorf("Expected the sync to start at %s, got %s", want, source.From)
+	}
+
+	source.Transactions = []sbanken.Transaction{archived, booked}
+	events, err = syncer.Sync(ctx, "A")
+	if err != nil || len(events) != 1 || events[0].Type != Removed || events[0].Transaction.Text != cancelled.Text {
+		t.Fatalf("Expected the cancelled reservation to be removed, got %+v (%v)", events, err)
+	}
+
+	changed := booked
+	changed.Text = "Til: BONNIER PUBLICA AS Betalt: 19.03.21"
+	source.Transactions = []sbanken.Transaction{archived, changed}
+	events, err = syncer.Sync(ctx, "A")
+	if err != nil || len(events) != 1 || events[0].Type != Updated || events[0].Previous.Text != booked.Text {
+		t.Fatalf("Expected the changed transaction to be updated, got %+v (%v)", events, err)
+	}
+
+	events, err = syncer.Sync(ctx, "A")
+	if err != nil || len(events) != 0 {
+		t.Errorf("Expected no events when nothing has changed, got %+v (%v)", events, err)
+	}
+}
+
+func TestSyncMemoryStore(t *testing.T) {
+	runScenario(t, NewMemoryStore())
+}
+
+func TestSyncFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbanken-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runScenario(t, store)
+	cur, err := store.Load("A")
+	if err != nil || len(cur.Seen) != 2 {
+		t.Errorf("Expected two transactions in the saved cursor, got %d (%v)", len(cur.Seen), err)
+	}
+}
@@ -0,0 +1,69 @@
+package sbanken
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+const transfers = "/Transfers"
+
+// maxTransferMessageLength is the longest message Sbanken accepts
+// on a transfer
+const maxTransferMessageLength = 30
+
+// TransferRequest moves Amount from one of your accounts to another
+type TransferRequest struct {
+	FromAccountID string `json:"fromAccountId"`
+	ToAccountID   string `json:"toAccountId"`
+	Amount        Amount `json:"amount"`
+	Message       string `json:"message"`
+}
+
+// TransferResult is returned when Sbanken has accepted a transfer
+type TransferResult struct {
+	TransferRequest
+	// TraceID identifies the transfer when contacting Sbanken support
+	TraceID string
+}
+
+type transferResponse struct {
+	errorInformation
+}
+
+// Validate checks the request before it is sent to Sbanken. The
+// returned error matches ErrValidation
+func (t TransferRequest) Validate() error {
+	if t.FromAccountID == "" || t.ToAccountID == "" {
+		return fmt.Errorf("Both the account to transfer from and to must be given: %w", ErrValidation)
+	}
+	if t.FromAccountID == t.ToAccountID {
+		return fmt.Errorf("Can not transfer to the same account: %w", ErrValidation)
+	}
+	if t.Amount <= 0 {
+		return fmt.Errorf("Transfer amount must be positive, got %s: %w", t.Amount, ErrValidation)
+	}
+	if utf8.RuneCountInString(t.Message) > maxTransferMessageLength {
+		return fmt.Errorf("Transfer message can not be longer than %d characters: %w", maxTransferMessageLength, ErrValidation)
+	}
+	return nil
+}
+
+// Transfer moves money between two of your own accounts. The request
+// is validated before it is sent, and Sbanken's rejections are
+// returned as an *APIError
+func (conn *APIConnection) Transfer(ctx context.Context, transfer TransferRequest) (TransferResult, error) {
+	if err := transfer.Validate(); err != nil {
+		return TransferResult{}, err
+	}
+	r := newAPIRequest(ctx)
+	r.method = http.MethodPost
+	r.target = conn.endpoint(transfers)
+	r.body = transfer
+	var a transferResponse
+	if err := conn.requestAction(r, &a); err != nil {
+		return TransferResult{}, err
+	}
+	return TransferResult{TransferRequest: transfer, TraceID: a.TraceID}, nil
+}
@@ -0,0 +1,100 @@
+package sbanken
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateAccountNumber checks that number is a valid Norwegian
+// account number: 11 digits where the last is a MOD11 check digit.
+// Spaces and dots, as in 9722.19.12345, are ignored. The returned
+// error matches ErrValidation
+func ValidateAccountNumber(number string) error {
+	digits := NormalizeAccountNumber(number)
+	if len(digits) != 11 || !isDigits(digits) {
+		return fmt.Errorf("Account number %q must be 11 digits: %w", number, ErrValidation)
+	}
+	if mod11(digits[:10], []int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}) != digits[10] {
+		return fmt.Errorf("Account number %q has an invalid check digit: %w", number, ErrValidation)
+	}
+	return nil
+}
+
+// ValidateKID checks that kid is a valid KID number, that is between
+// 2 and 25 digits with a MOD10 or MOD11 check digit. A MOD11 check
+// digit of 10 is written as '-'. The returned error matches ErrValidation
+func ValidateKID(kid string) error {
+	if len(kid) < 2 || len(kid) > 25 {
+		return fmt.Errorf("KID %q must be between 2 and 25 characters: %w", kid, ErrValidation)
+	}
+	body, check := kid[:len(kid)-1], kid[len(kid)-1]
+	if !isDigits(body) || !(isDigits(string(check)) || check == '-') {
+		return fmt.Errorf("KID %q can only contain digits: %w", kid, ErrValidation)
+	}
+	if mod10(body) == check || mod11(body, kidWeights(len(body))) == check {
+		return nil
+	}
+	return fmt.Errorf("KID %q has an invalid check digit: %w", kid, ErrValidation)
+}
+
+// mod10 calculates the Luhn check digit for digits
+func mod10(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// mod11 calculates the MOD11 check digit for digits using weights,
+// returning '-' when the check digit would be 10
+func mod11(digits string, weights []int) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * weights[i]
+	}
+	switch check := 11 - sum%11; check {
+	case 11:
+		return '0'
+	case 10:
+		return '-'
+	default:
+		return byte('0' + check)
+	}
+}
+
+// kidWeights returns the MOD11 weights for a KID body of length n,
+// which are 2, 3, 4, 5, 6, 7, 2, 3, ... counted from the right
+func kidWeights(n int) []int {
+	weights := make([]int, n)
+	for i := 0; i < n; i++ {
+		weights[n-1-i] = 2 + i%6
+	}
+	return weights
+}
+
+// NormalizeAccountNumber removes the spaces and dots account numbers
+// are often written with, so 9722.19.12345 becomes 97221912345. It does
+// not validate the number, see ValidateAccountNumber
+func NormalizeAccountNumber(number string) string {
+	return strings.NewReplacer(" ", "", ".", "").Replace(number)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}